@@ -0,0 +1,469 @@
+package pouch
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"time"
+)
+
+// An AuditRecord describes a write to an entity.
+type AuditRecord struct {
+	// Entity is the table of the entity that was written to.
+	Entity string
+	Op     Operation
+	// Identity holds the identifying columns of the entity, it is nil
+	// for UpdateWhere and DeleteWhere, which write to many entities.
+	Identity map[string]interface{}
+	// Before holds the insertable columns of the entity before it was
+	// written to, it is nil for creates.
+	Before map[string]interface{}
+	// After holds the insertable columns of the entity after it was
+	// written to, limited to the columns an update wrote (the assignments
+	// for UpdateWhere), it is nil for deletes.
+	After map[string]interface{}
+	// Actor is the caller the write was done on behalf of, see WithCaller.
+	Actor interface{}
+	At    time.Time
+}
+
+// An AuditSink keeps AuditRecords, it is meant to never change or drop
+// the records it is given.
+type AuditSink interface {
+	Record(ctx context.Context, r *AuditRecord) error
+}
+
+// AuditPouch returns a view of the given Pouch which records every write
+// (Create, Update, Delete, their *All, UpdateColumns, HardDelete, Restore,
+// UpdateWhere and DeleteWhere) to the given sink. The values an entity
+// had before it was written to are found through the Pouch, so written
+// entities should be Findable. Updates of Trackable entities are recorded
+// with the columns that changed since they were snapshotted, and not at
+// all when none did, as no statement is run for them.
+//
+// Records are made after the write succeeded, and an error recording
+// it is returned as the write's error. Writes of many entities that fail
+// with a *ConflictError or *NotFoundError are recorded for the entities
+// the error doesn't name; those that fail with any other error are not
+// recorded at all, even though the pouch may have written some of their
+// entities before it failed, as the error doesn't say which. Run them in
+// a transaction, through the view's RunInTx if the Pouch is
+// Transactional, to leave no such writes behind.
+func AuditPouch(inner Pouch, sink AuditSink) Pouch {
+	return newAuditQuery(blank(inner), inner, sink)
+}
+
+type auditQuery struct {
+	decorator
+	// the pouch, for finding entities by their identity
+	pouch Pouch
+	sink  AuditSink
+}
+
+func newAuditQuery(inner Query, p Pouch, sink AuditSink) *auditQuery {
+	return &auditQuery{
+		decorator: decorator{
+			inner: inner,
+			wrap: func(q Query) Query {
+				return newAuditQuery(q, p, sink)
+			},
+			within: func(tx Pouch) Query {
+				return newAuditQuery(blank(tx), tx, sink)
+			},
+		},
+		pouch: p,
+		sink:  sink,
+	}
+}
+
+// record writes a record for each entity, skipping the ones that the
+// error of the write they were in says weren't written to. The columns
+// each entity had written, if given, limit what is recorded after.
+func (s *auditQuery) record(op Operation, es []Tableable, befores []map[string]interface{}, written [][]string, err error) error {
+	var skipped []Tableable
+	switch e := err.(type) {
+	case nil:
+	case *ConflictError:
+		for _, u := range e.Conflicts {
+			skipped = append(skipped, u)
+		}
+	case *NotFoundError:
+		for _, d := range e.Missing {
+			skipped = append(skipped, d)
+		}
+	default:
+		return err
+	}
+
+	for i, e := range es {
+		if anySame(skipped, e) {
+			continue
+		}
+		var after map[string]interface{}
+		if ins, ok := e.(Insertable); ok && op != OpDelete {
+			after = columns(ins.InsertableFields())
+			if written != nil && written[i] != nil {
+				after = only(after, written[i])
+			}
+		}
+		var identity map[string]interface{}
+		if id, ok := e.(Identifiable); ok {
+			identity = columns(id.IdentifiableFields())
+		}
+		if rerr := s.write(e.Table(), op, identity, befores[i], after); rerr != nil {
+			return rerr
+		}
+	}
+	return err
+}
+
+// anySame reports whether any of the entities is the given one: the same
+// pointer, or for entities that aren't pointers (which need not be
+// comparable), one with the same identity.
+func anySame(es []Tableable, e Tableable) bool {
+	v := reflect.ValueOf(e)
+	for _, other := range es {
+		o := reflect.ValueOf(other)
+		if o.Type() != v.Type() {
+			continue
+		}
+		if v.Kind() == reflect.Ptr {
+			if o.Pointer() == v.Pointer() {
+				return true
+			}
+			continue
+		}
+		if sameIdentity(other, e) {
+			return true
+		}
+	}
+	return false
+}
+
+func sameIdentity(a, b Tableable) bool {
+	ia, ok := a.(Identifiable)
+	if !ok {
+		return false
+	}
+	ib, ok := b.(Identifiable)
+	if !ok {
+		return false
+	}
+	_, x := ia.IdentifiableFields()
+	_, y := ib.IdentifiableFields()
+	if len(x) == 0 || len(x) != len(y) {
+		return false
+	}
+	for i := range x {
+		if keyOf(x[i]) != keyOf(y[i]) {
+			return false
+		}
+	}
+	return true
+}
+
+// only returns the given columns of a record's columns.
+func only(m map[string]interface{}, cols []string) map[string]interface{} {
+	var kept = make(map[string]interface{}, len(cols))
+	for _, col := range cols {
+		if v, ok := m[col]; ok {
+			kept[col] = v
+		}
+	}
+	return kept
+}
+
+// written returns the columns an update of an entity writes, given the
+// columns it is limited to, if any, or nil if it writes all of them. It
+// reports false for Trackable entities that haven't changed, which
+// aren't written to at all.
+func written(u Updateable, cols []string) ([]string, bool) {
+	if len(cols) == 0 {
+		tracked, ok := u.(Trackable)
+		if !ok {
+			return nil, true
+		}
+		changed, ok := tracked.Changed(u.InsertableFields())
+		if !ok {
+			return nil, true
+		}
+		if len(changed) == 0 {
+			return nil, false
+		}
+		cols = changed
+	}
+	if v, ok := u.(Versioned); ok {
+		col, _ := v.VersionField()
+		cols = append(cols[:len(cols):len(cols)], col)
+	}
+	return cols, true
+}
+
+func (s *auditQuery) write(table string, op Operation, identity, before, after map[string]interface{}) error {
+	ctx := s.inner.Context()
+	actor, _ := CallerFrom(ctx)
+	return s.sink.Record(ctx, &AuditRecord{
+		Entity:   table,
+		Op:       op,
+		Identity: identity,
+		Before:   before,
+		After:    after,
+		Actor:    actor,
+		At:       time.Now(),
+	})
+}
+
+// before finds the insertable columns stored for an entity, soft deleted
+// or not, before it is written to.
+func (s *auditQuery) before(e Identifiable) (map[string]interface{}, error) {
+	f, ok := e.(Findable)
+	if !ok {
+		return nil, nil
+	}
+	var stored = f.FindableCopy()
+	cols, vals := f.IdentifiableFields()
+	for i, col := range cols {
+		if err := setColumn(stored, col, vals[i]); err != nil {
+			return nil, err
+		}
+	}
+
+	// writing to an entity that isn't stored is left up to the pouch
+	q := s.pouch.WithContext(s.inner.Context()).WithDeleted()
+	if err := q.Find(stored); err == sql.ErrNoRows {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+	if ins, ok := stored.(Insertable); ok {
+		return columns(ins.InsertableFields()), nil
+	}
+	return nil, nil
+}
+
+func (s *auditQuery) befores(es []Identifiable) ([]map[string]interface{}, error) {
+	var befores = make([]map[string]interface{}, len(es))
+	for i, e := range es {
+		var err error
+		if befores[i], err = s.before(e); err != nil {
+			return nil, err
+		}
+	}
+	return befores, nil
+}
+
+func (s *auditQuery) Create(c Createable) error {
+	return s.record(OpCreate, []Tableable{c}, make([]map[string]interface{}, 1), nil, s.inner.Create(c))
+}
+
+func (s *auditQuery) CreateAll(cs []Createable) error {
+	var es = make([]Tableable, len(cs))
+	for i, c := range cs {
+		es[i] = c
+	}
+	return s.record(OpCreate, es, make([]map[string]interface{}, len(cs)), nil, s.inner.CreateAll(cs))
+}
+
+func (s *auditQuery) Update(u Updateable) error {
+	cols, ok := written(u, nil)
+	if !ok {
+		return s.inner.Update(u)
+	}
+	before, err := s.before(u)
+	if err != nil {
+		return err
+	}
+	return s.record(OpUpdate, []Tableable{u}, []map[string]interface{}{before}, [][]string{cols}, s.inner.Update(u))
+}
+
+func (s *auditQuery) UpdateColumns(u Updateable, cols ...string) error {
+	wrote, ok := written(u, cols)
+	if !ok {
+		return s.inner.UpdateColumns(u, cols...)
+	}
+	before, err := s.before(u)
+	if err != nil {
+		return err
+	}
+	return s.record(OpUpdate, []Tableable{u}, []map[string]interface{}{before}, [][]string{wrote}, s.inner.UpdateColumns(u, cols...))
+}
+
+func (s *auditQuery) UpdateAll(us []Updateable) error {
+	var (
+		es    []Tableable
+		ids   []Identifiable
+		wrote [][]string
+	)
+	for _, u := range us {
+		if cols, ok := written(u, nil); ok {
+			es, ids, wrote = append(es, u), append(ids, u), append(wrote, cols)
+		}
+	}
+	befores, err := s.befores(ids)
+	if err != nil {
+		return err
+	}
+	return s.record(OpUpdate, es, befores, wrote, s.inner.UpdateAll(us))
+}
+
+func (s *auditQuery) Delete(d Deleteable) error {
+	before, err := s.before(d)
+	if err != nil {
+		return err
+	}
+	return s.record(OpDelete, []Tableable{d}, []map[string]interface{}{before}, nil, s.inner.Delete(d))
+}
+
+func (s *auditQuery) DeleteAll(ds []Deleteable) error {
+	var (
+		es  = make([]Tableable, len(ds))
+		ids = make([]Identifiable, len(ds))
+	)
+	for i, d := range ds {
+		es[i], ids[i] = d, d
+	}
+	befores, err := s.befores(ids)
+	if err != nil {
+		return err
+	}
+	return s.record(OpDelete, es, befores, nil, s.inner.DeleteAll(ds))
+}
+
+func (s *auditQuery) HardDelete(d Deleteable) error {
+	before, err := s.before(d)
+	if err != nil {
+		return err
+	}
+	return s.record(OpDelete, []Tableable{d}, []map[string]interface{}{before}, nil, s.inner.HardDelete(d))
+}
+
+func (s *auditQuery) Restore(sd SoftDeleteable) error {
+	before, err := s.before(sd)
+	if err != nil {
+		return err
+	}
+	return s.record(OpUpdate, []Tableable{sd}, []map[string]interface{}{before}, [][]string{{sd.DeletedAtColumn()}}, s.inner.Restore(sd))
+}
+
+func (s *auditQuery) UpdateWhere(t Tableable, assignments map[string]interface{}) (int64, error) {
+	n, err := s.inner.UpdateWhere(t, assignments)
+	if err != nil {
+		return n, err
+	}
+	return n, s.write(t.Table(), OpUpdate, nil, nil, assignments)
+}
+
+func (s *auditQuery) DeleteWhere(t Tableable) (int64, error) {
+	n, err := s.inner.DeleteWhere(t)
+	if err != nil {
+		return n, err
+	}
+	return n, s.write(t.Table(), OpDelete, nil, nil, nil)
+}
+
+// columns pairs up columns and their values, dereferencing pointers.
+func columns(cols []string, vals []interface{}) map[string]interface{} {
+	var m = make(map[string]interface{}, len(cols))
+	for i, col := range cols {
+		if i < len(vals) {
+			m[col], _ = deref(vals[i])
+		}
+	}
+	return m
+}
+
+// PouchAuditSink returns an AuditSink which creates a row in the given
+// table for every record through the given Pouch. The table has the
+// columns Entity, Op, Identity, Before, After, Actor and At, and an
+// auto incremented ID; columns of records are stored as JSON.
+func PouchAuditSink(p Pouch, table string) AuditSink {
+	return &pouchAuditSink{p: p, table: table}
+}
+
+type pouchAuditSink struct {
+	p     Pouch
+	table string
+}
+
+func (s *pouchAuditSink) Record(ctx context.Context, r *AuditRecord) error {
+	entry := &auditEntry{table: s.table, Entity: r.Entity, Op: r.Op.String(), At: r.At}
+	if r.Actor != nil {
+		entry.Actor = fmt.Sprint(r.Actor)
+	}
+	for _, c := range []struct {
+		dst *string
+		src map[string]interface{}
+	}{
+		{&entry.Identity, r.Identity},
+		{&entry.Before, r.Before},
+		{&entry.After, r.After},
+	} {
+		if c.src == nil {
+			continue
+		}
+		b, err := json.Marshal(c.src)
+		if err != nil {
+			return err
+		}
+		*c.dst = string(b)
+	}
+	return s.p.WithContext(ctx).Create(entry)
+}
+
+// auditEntry is the row an AuditRecord is stored as by a pouchAuditSink.
+type auditEntry struct {
+	table    string
+	ID       int64
+	Entity   string
+	Op       string
+	Identity string
+	Before   string
+	After    string
+	Actor    string
+	At       time.Time
+}
+
+var auditColumns = []string{"Entity", "Op", "Identity", "Before", "After", "Actor", "At"}
+
+func (a *auditEntry) Table() string { return a.table }
+
+func (a *auditEntry) InsertableFields() ([]string, []interface{}) {
+	return auditColumns, a.FieldsFor(auditColumns)
+}
+
+func (a *auditEntry) FieldsFor(cols []string) []interface{} {
+	var vals = make([]interface{}, len(cols))
+	for i, col := range cols {
+		switch col {
+		case "ID":
+			vals[i] = a.ID
+		case "Entity":
+			vals[i] = a.Entity
+		case "Op":
+			vals[i] = a.Op
+		case "Identity":
+			vals[i] = a.Identity
+		case "Before":
+			vals[i] = a.Before
+		case "After":
+			vals[i] = a.After
+		case "Actor":
+			vals[i] = a.Actor
+		case "At":
+			vals[i] = a.At
+		}
+	}
+	return vals
+}
+
+func (a *auditEntry) SetIdentifier(i interface{}) error {
+	id, ok := i.(int64)
+	if !ok {
+		return errors.New("audit entries are identified by an int64")
+	}
+	a.ID = id
+	return nil
+}
@@ -0,0 +1,339 @@
+package pouch
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"reflect"
+)
+
+type callerKey struct{}
+
+// WithCaller returns a context which carries the identity of the caller
+// operations are done on behalf of, for Authorizers and audit records.
+func WithCaller(ctx context.Context, caller interface{}) context.Context {
+	return context.WithValue(ctx, callerKey{}, caller)
+}
+
+// CallerFrom returns the identity of the caller the given context
+// carries, if any.
+func CallerFrom(ctx context.Context) (interface{}, bool) {
+	caller := ctx.Value(callerKey{})
+	return caller, caller != nil
+}
+
+// An Authorizer decides whether the caller (see CallerFrom) may do an
+// operation on an entity, i.e. "users may only update their own
+// profile". It returns nil to allow the operation, or the error to
+// refuse it with.
+type Authorizer interface {
+	Authorize(ctx context.Context, op Operation, entity Tableable) error
+}
+
+// AuthorizerFunc lets an ordinary function act as an Authorizer.
+type AuthorizerFunc func(ctx context.Context, op Operation, entity Tableable) error
+
+func (f AuthorizerFunc) Authorize(ctx context.Context, op Operation, entity Tableable) error {
+	return f(ctx, op, entity)
+}
+
+// Authorized returns a view of the given Pouch which asks the Authorizer,
+// with the context of the Query (see Queryable.WithContext), before every
+// write to an entity and after every read of one. Refused writes and
+// Finds fail with the Authorizer's error, while entities the caller may
+// not read are left out of the results of FindEntities and FindPage.
+//
+// Writes are authorized for what is stored for the entity, soft deleted
+// or not, rather than what the caller handed in, so the entities must be
+// Findable; updates are authorized for the updated entity as well. Find
+// and FindAll find into copies of the entities, which are only copied
+// into the given ones once they are authorized.
+//
+// Relations preloaded for the entities that are found are read through
+// the view as well, so related entities the caller may not read are
+// left out of them.
+//
+// UpdateWhere and DeleteWhere change entities that are never read, so
+// they fail with a *PermissionError unless the Authorizer is a
+// BulkAuthorizer, which decides on them instead.
+func Authorized(p Pouch, a Authorizer) Pouch {
+	s := &authorizedQuery{
+		pouch: p,
+		auth:  a,
+	}
+	return s.with(blank(p))
+}
+
+// A BulkAuthorizer is an Authorizer which may also allow UpdateWhere and
+// DeleteWhere, given the example entity they were handed.
+type BulkAuthorizer interface {
+	Authorizer
+	AuthorizeBulk(ctx context.Context, op Operation, example Tableable) error
+}
+
+type authorizedQuery struct {
+	decorator
+	// the pouch, for finding what is stored for written entities and
+	// preloading relations
+	pouch    Pouch
+	auth     Authorizer
+	preloads []string
+}
+
+// with returns a copy of the query, wrapping the given one.
+func (s *authorizedQuery) with(inner Query) *authorizedQuery {
+	c := *s
+	c.preloads = c.preloads[:len(c.preloads):len(c.preloads)]
+	c.decorator = decorator{
+		inner: inner,
+		wrap: func(q Query) Query {
+			return c.with(q)
+		},
+		within: func(tx Pouch) Query {
+			return Authorized(tx, c.auth).(Query)
+		},
+	}
+	return &c
+}
+
+// Preload has the query preload the relations itself, through the
+// authorized view of the pouch, rather than the wrapped query which
+// would find them through the bare pouch.
+func (s *authorizedQuery) Preload(relations ...string) Query {
+	q := s.with(s.inner)
+	q.preloads = append(q.preloads, relations...)
+	return q
+}
+
+// preload preloads the relations of the entities found past the given
+// index.
+func (s *authorizedQuery) preload(res *[]Findable, from int) error {
+	if len(s.preloads) == 0 {
+		return nil
+	}
+	return Preload(Authorized(s.pouch, s.auth).WithContext(s.inner.Context()), (*res)[from:], s.preloads...)
+}
+
+func (s *authorizedQuery) authorize(op Operation, e Tableable) error {
+	return s.auth.Authorize(s.inner.Context(), op, e)
+}
+
+// authorizeStored authorizes an operation on what is stored for an
+// entity that is being written to. Writing to an entity that isn't
+// stored is authorized for the entity itself.
+func (s *authorizedQuery) authorizeStored(op Operation, e Identifiable) error {
+	f, ok := e.(Findable)
+	if !ok {
+		return errors.New("cannot authorize writes to an entity that isn't Findable")
+	}
+	var stored = f.FindableCopy()
+	cols, vals := f.IdentifiableFields()
+	for i, col := range cols {
+		if err := setColumn(stored, col, vals[i]); err != nil {
+			return err
+		}
+	}
+
+	q := s.pouch.WithContext(s.inner.Context()).WithDeleted()
+	if err := q.Find(stored); err == sql.ErrNoRows {
+		return s.authorize(op, f)
+	} else if err != nil {
+		return err
+	}
+	return s.authorize(op, stored)
+}
+
+// authorizeUpdate authorizes an update of what is stored for an entity,
+// and of what it's being updated to.
+func (s *authorizedQuery) authorizeUpdate(u Updateable) error {
+	if err := s.authorizeStored(OpUpdate, u); err != nil {
+		return err
+	}
+	return s.authorize(OpUpdate, u)
+}
+
+// copyEntity copies what one entity holds into another of its kind.
+func copyEntity(dst, src Findable) error {
+	d, v := reflect.ValueOf(dst), reflect.ValueOf(src)
+	if d.Kind() != reflect.Ptr || d.Type() != v.Type() || d.IsNil() || v.IsNil() {
+		return fmt.Errorf("cannot copy a %T into a %T", src, dst)
+	}
+	d.Elem().Set(v.Elem())
+	return nil
+}
+
+// findable returns a copy of an entity to find into, in its stead.
+func findable(f Findable) (Findable, error) {
+	c := f.FindableCopy()
+	if err := copyEntity(c, f); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+// filter drops the entities the caller may not read from the results
+// found past the given index.
+func (s *authorizedQuery) filter(res *[]Findable, from int) {
+	var kept = (*res)[:from]
+	for _, f := range (*res)[from:] {
+		if s.authorize(OpRead, f) == nil {
+			kept = append(kept, f)
+		}
+	}
+	*res = kept
+}
+
+func (s *authorizedQuery) Find(i Findable) error {
+	found, err := findable(i)
+	if err != nil {
+		return err
+	}
+	if err := s.inner.Find(found); err != nil {
+		return err
+	}
+	if err := s.authorize(OpRead, found); err != nil {
+		return err
+	}
+	return copyEntity(i, found)
+}
+
+func (s *authorizedQuery) FindAll(fs []Findable) error {
+	var found = make([]Findable, len(fs))
+	for i, f := range fs {
+		c, err := findable(f)
+		if err != nil {
+			return err
+		}
+		found[i] = c
+	}
+	if err := s.inner.FindAll(found); err != nil {
+		return err
+	}
+	for _, f := range found {
+		if err := s.authorize(OpRead, f); err != nil {
+			return err
+		}
+	}
+	for i, f := range found {
+		if err := copyEntity(fs[i], f); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *authorizedQuery) FindEntities(template Findable, res *[]Findable) error {
+	var found = len(*res)
+	if err := s.inner.FindEntities(template, res); err != nil {
+		return err
+	}
+	s.filter(res, found)
+	return s.preload(res, found)
+}
+
+func (s *authorizedQuery) FindPage(template Findable, res *[]Findable) (Cursor, Cursor, error) {
+	var found = len(*res)
+	next, prev, err := s.inner.FindPage(template, res)
+	if err != nil {
+		return "", "", err
+	}
+	s.filter(res, found)
+	if err := s.preload(res, found); err != nil {
+		return "", "", err
+	}
+	return next, prev, nil
+}
+
+func (s *authorizedQuery) Create(c Createable) error {
+	if err := s.authorize(OpCreate, c); err != nil {
+		return err
+	}
+	return s.inner.Create(c)
+}
+
+func (s *authorizedQuery) CreateAll(cs []Createable) error {
+	for _, c := range cs {
+		if err := s.authorize(OpCreate, c); err != nil {
+			return err
+		}
+	}
+	return s.inner.CreateAll(cs)
+}
+
+func (s *authorizedQuery) Update(u Updateable) error {
+	if err := s.authorizeUpdate(u); err != nil {
+		return err
+	}
+	return s.inner.Update(u)
+}
+
+func (s *authorizedQuery) UpdateColumns(u Updateable, cols ...string) error {
+	if err := s.authorizeUpdate(u); err != nil {
+		return err
+	}
+	return s.inner.UpdateColumns(u, cols...)
+}
+
+func (s *authorizedQuery) UpdateAll(us []Updateable) error {
+	for _, u := range us {
+		if err := s.authorizeUpdate(u); err != nil {
+			return err
+		}
+	}
+	return s.inner.UpdateAll(us)
+}
+
+func (s *authorizedQuery) Delete(d Deleteable) error {
+	if err := s.authorizeStored(OpDelete, d); err != nil {
+		return err
+	}
+	return s.inner.Delete(d)
+}
+
+func (s *authorizedQuery) DeleteAll(ds []Deleteable) error {
+	for _, d := range ds {
+		if err := s.authorizeStored(OpDelete, d); err != nil {
+			return err
+		}
+	}
+	return s.inner.DeleteAll(ds)
+}
+
+func (s *authorizedQuery) HardDelete(d Deleteable) error {
+	if err := s.authorizeStored(OpDelete, d); err != nil {
+		return err
+	}
+	return s.inner.HardDelete(d)
+}
+
+func (s *authorizedQuery) Restore(sd SoftDeleteable) error {
+	if err := s.authorizeStored(OpUpdate, sd); err != nil {
+		return err
+	}
+	return s.inner.Restore(sd)
+}
+
+// authorizeBulk authorizes an operation on the entities matching an
+// example entity, which only a BulkAuthorizer may allow.
+func (s *authorizedQuery) authorizeBulk(op Operation, t Tableable) error {
+	b, ok := s.auth.(BulkAuthorizer)
+	if !ok {
+		return &PermissionError{Table: t.Table(), Op: op}
+	}
+	return b.AuthorizeBulk(s.inner.Context(), op, t)
+}
+
+func (s *authorizedQuery) UpdateWhere(t Tableable, assignments map[string]interface{}) (int64, error) {
+	if err := s.authorizeBulk(OpUpdate, t); err != nil {
+		return 0, err
+	}
+	return s.inner.UpdateWhere(t, assignments)
+}
+
+func (s *authorizedQuery) DeleteWhere(t Tableable) (int64, error) {
+	if err := s.authorizeBulk(OpDelete, t); err != nil {
+		return 0, err
+	}
+	return s.inner.DeleteWhere(t)
+}
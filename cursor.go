@@ -0,0 +1,405 @@
+package pouch
+
+import (
+	"bytes"
+	"database/sql/driver"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// A Cursor is an opaque, URL safe token marking a position in the
+// results of an ordered Query, it holds the values of the columns
+// the results are ordered by for the entity at that position. Cursors
+// are used for keyset pagination, see Queryable.After, Queryable.Before
+// and Query.FindPage.
+type Cursor string
+
+// An OrderColumn is a column results are ordered by, and its direction.
+type OrderColumn struct {
+	Column string
+	Desc   bool
+}
+
+// KeysetOrder parses the given order by specs (i.e. "Name desc, ID")
+// into the columns results are ordered by for keyset pagination, the
+// identifying columns of the given entity are added to make the order
+// unique if they aren't there already.
+func KeysetOrder(orderBy []string, i Identifiable) []OrderColumn {
+	var (
+		order []OrderColumn
+		seen  = make(map[string]struct{})
+	)
+	for _, spec := range orderBy {
+		for _, part := range strings.Split(spec, ",") {
+			words := strings.Fields(part)
+			if len(words) == 0 {
+				continue
+			}
+			order = append(order, OrderColumn{
+				Column: words[0],
+				Desc:   len(words) > 1 && strings.EqualFold(words[1], "desc"),
+			})
+			seen[words[0]] = struct{}{}
+		}
+	}
+
+	ids, _ := i.IdentifiableFields()
+	for _, id := range ids {
+		if _, ok := seen[id]; !ok {
+			order = append(order, OrderColumn{Column: id})
+		}
+	}
+	return order
+}
+
+// the kinds of values cursors hold, which they're encoded with so they
+// decode to the same kind
+const (
+	cursorInt    = "i"
+	cursorUint   = "u"
+	cursorFloat  = "f"
+	cursorString = "s"
+	cursorBool   = "b"
+	cursorTime   = "t"
+)
+
+// CursorFor returns the cursor marking the given entity's position in
+// results ordered by the given columns.
+func CursorFor(g Gettable, order []OrderColumn) (Cursor, error) {
+	var vals = make([][2]string, len(order))
+	for i, o := range order {
+		v, ok := columnValue(g, o.Column)
+		if !ok {
+			return "", errors.New("cannot paginate by null or unknown column: " + o.Column)
+		}
+		kind, val, err := cursorValue(v)
+		if err != nil {
+			return "", fmt.Errorf("cannot paginate by column %s: %v", o.Column, err)
+		}
+		vals[i] = [2]string{kind, val}
+	}
+
+	b, err := json.Marshal(vals)
+	if err != nil {
+		return "", err
+	}
+	return Cursor(base64.RawURLEncoding.EncodeToString(b)), nil
+}
+
+// cursorValue encodes a column value for a cursor, along with its kind.
+func cursorValue(v interface{}) (kind, val string, err error) {
+	if valuer, ok := v.(driver.Valuer); ok {
+		if v, err = valuer.Value(); err != nil {
+			return "", "", err
+		}
+	}
+	switch n := v.(type) {
+	case int, int8, int16, int32, int64:
+		return cursorInt, fmt.Sprint(n), nil
+	case uint, uint8, uint16, uint32, uint64:
+		return cursorUint, fmt.Sprint(n), nil
+	case float32:
+		return cursorFloat, strconv.FormatFloat(float64(n), 'g', -1, 32), nil
+	case float64:
+		return cursorFloat, strconv.FormatFloat(n, 'g', -1, 64), nil
+	case string:
+		return cursorString, n, nil
+	case []byte:
+		return cursorString, string(n), nil
+	case bool:
+		return cursorBool, strconv.FormatBool(n), nil
+	case time.Time:
+		return cursorTime, n.Format(time.RFC3339Nano), nil
+	}
+	return "", "", fmt.Errorf("unsupported type %T", v)
+}
+
+// Values decodes the column values held by the cursor, as the kind of
+// value they were encoded from: integers are decoded as int64 (uint64
+// if they were unsigned), other numbers as float64, text as strings,
+// and times as time.Time.
+func (c Cursor) Values() ([]interface{}, error) {
+	b, err := base64.RawURLEncoding.DecodeString(string(c))
+	if err != nil {
+		return nil, errors.New("malformed cursor")
+	}
+
+	var encoded [][2]string
+	dec := json.NewDecoder(bytes.NewReader(b))
+	if err := dec.Decode(&encoded); err != nil {
+		return nil, errors.New("malformed cursor")
+	}
+
+	var vals = make([]interface{}, len(encoded))
+	for i, e := range encoded {
+		switch kind, val := e[0], e[1]; kind {
+		case cursorInt:
+			vals[i], err = strconv.ParseInt(val, 10, 64)
+		case cursorUint:
+			vals[i], err = strconv.ParseUint(val, 10, 64)
+		case cursorFloat:
+			vals[i], err = strconv.ParseFloat(val, 64)
+		case cursorString:
+			vals[i] = val
+		case cursorBool:
+			vals[i], err = strconv.ParseBool(val)
+		case cursorTime:
+			vals[i], err = time.Parse(time.RFC3339Nano, val)
+		default:
+			err = errors.New("unknown kind")
+		}
+		if err != nil {
+			return nil, errors.New("malformed cursor")
+		}
+	}
+	return vals, nil
+}
+
+// PageCursors returns the cursors for the pages after and before a page
+// of results, found with the given cursors and limit. There is no next
+// cursor when paging forward runs out of results, nor a previous one
+// on the first page or when paging backward runs out of results; with
+// no limit, the results always run out.
+func PageCursors(page []Findable, order []OrderColumn, after, before Cursor, limit int) (next, prev Cursor, err error) {
+	if len(page) == 0 {
+		return "", "", nil
+	}
+
+	var short = limit <= 0 || len(page) < limit
+	if len(before) > 0 || !short {
+		if next, err = CursorFor(page[len(page)-1], order); err != nil {
+			return "", "", err
+		}
+	}
+	if (len(after) > 0 && len(before) == 0) || (len(before) > 0 && !short) {
+		if prev, err = CursorFor(page[0], order); err != nil {
+			return "", "", err
+		}
+	}
+	return next, prev, nil
+}
+
+// PageEntities orders entities that are already in memory by the given
+// columns, then applies keyset pagination, the offset and the limit to
+// them, as a Query backed by a database would. It is meant for Queries
+// whose backing storage can't do so itself.
+func PageEntities(es []Findable, order []OrderColumn, after, before Cursor, offset, limit int) ([]Findable, error) {
+	var sortErr error
+	var sorted = append([]Findable(nil), es...)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		c, err := compareEntities(sorted[i], sorted[j], order)
+		if err != nil {
+			sortErr = err
+		}
+		return c < 0
+	})
+	if sortErr != nil {
+		return nil, sortErr
+	}
+
+	var page []Findable
+	for _, e := range sorted {
+		ok, err := beyondCursor(e, order, after, 1)
+		if err != nil {
+			return nil, err
+		}
+		if ok {
+			if ok, err = beyondCursor(e, order, before, -1); err != nil {
+				return nil, err
+			}
+		}
+		if ok {
+			page = append(page, e)
+		}
+	}
+
+	// paging backward takes the entities closest to the cursor
+	if len(before) > 0 && len(after) == 0 && limit > 0 && len(page) > offset+limit {
+		return page[len(page)-offset-limit : len(page)-offset], nil
+	}
+
+	if offset >= len(page) {
+		return nil, nil
+	}
+	page = page[offset:]
+	if limit > 0 && len(page) > limit {
+		page = page[:limit]
+	}
+	return page, nil
+}
+
+// beyondCursor reports whether the entity comes after (dir 1) or before
+// (dir -1) the given cursor, which is always the case for no cursor.
+func beyondCursor(e Findable, order []OrderColumn, c Cursor, dir int) (bool, error) {
+	if len(c) == 0 {
+		return true, nil
+	}
+
+	vals, err := c.Values()
+	if err != nil {
+		return false, err
+	}
+	if len(vals) != len(order) {
+		return false, errors.New("cursor does not match the query's order")
+	}
+
+	for i, o := range order {
+		v, ok := columnValue(e, o.Column)
+		if !ok {
+			return false, errors.New("cannot paginate by null or unknown column: " + o.Column)
+		}
+		cmp, err := compareValues(v, vals[i])
+		if err != nil {
+			return false, err
+		}
+		if o.Desc {
+			cmp = -cmp
+		}
+		if cmp != 0 {
+			return cmp == dir, nil
+		}
+	}
+	return false, nil
+}
+
+func compareEntities(a, b Findable, order []OrderColumn) (int, error) {
+	for _, o := range order {
+		av, aok := columnValue(a, o.Column)
+		bv, bok := columnValue(b, o.Column)
+		if !aok || !bok {
+			return 0, errors.New("cannot order by null or unknown column: " + o.Column)
+		}
+		cmp, err := compareValues(av, bv)
+		if err != nil {
+			return 0, err
+		}
+		if o.Desc {
+			cmp = -cmp
+		}
+		if cmp != 0 {
+			return cmp, nil
+		}
+	}
+	return 0, nil
+}
+
+// compareValues compares column values, which may be of different
+// types if one of them was decoded from a cursor. Integers are compared
+// exactly, whatever their size.
+func compareValues(a, b interface{}) (int, error) {
+	if ai, ok := toInt(a); ok {
+		if bi, ok := toInt(b); ok {
+			return ai.Cmp(bi), nil
+		}
+	}
+	if at, ok := a.(time.Time); ok {
+		if bt, ok := b.(time.Time); ok {
+			switch {
+			case at.Before(bt):
+				return -1, nil
+			case at.After(bt):
+				return 1, nil
+			}
+			return 0, nil
+		}
+	}
+	if af, ok := toFloat(a); ok {
+		if bf, ok := toFloat(b); ok {
+			switch {
+			case af < bf:
+				return -1, nil
+			case af > bf:
+				return 1, nil
+			}
+			return 0, nil
+		}
+	}
+
+	as, aok := toString(a)
+	bs, bok := toString(b)
+	if !aok || !bok {
+		return 0, errors.New("cannot compare column values")
+	}
+	return strings.Compare(as, bs), nil
+}
+
+func toInt(v interface{}) (*big.Int, bool) {
+	switch n := v.(type) {
+	case int:
+		return big.NewInt(int64(n)), true
+	case int8:
+		return big.NewInt(int64(n)), true
+	case int16:
+		return big.NewInt(int64(n)), true
+	case int32:
+		return big.NewInt(int64(n)), true
+	case int64:
+		return big.NewInt(n), true
+	case uint:
+		return new(big.Int).SetUint64(uint64(n)), true
+	case uint8:
+		return new(big.Int).SetUint64(uint64(n)), true
+	case uint16:
+		return new(big.Int).SetUint64(uint64(n)), true
+	case uint32:
+		return new(big.Int).SetUint64(uint64(n)), true
+	case uint64:
+		return new(big.Int).SetUint64(n), true
+	}
+	return nil, false
+}
+
+func toFloat(v interface{}) (float64, bool) {
+	switch n := v.(type) {
+	case int:
+		return float64(n), true
+	case int8:
+		return float64(n), true
+	case int16:
+		return float64(n), true
+	case int32:
+		return float64(n), true
+	case int64:
+		return float64(n), true
+	case uint:
+		return float64(n), true
+	case uint8:
+		return float64(n), true
+	case uint16:
+		return float64(n), true
+	case uint32:
+		return float64(n), true
+	case uint64:
+		return float64(n), true
+	case float32:
+		return float64(n), true
+	case float64:
+		return n, true
+	}
+	return 0, false
+}
+
+func toString(v interface{}) (string, bool) {
+	switch s := v.(type) {
+	case string:
+		return s, true
+	case []byte:
+		return string(s), true
+	case time.Time:
+		// the same format times are encoded with in cursors
+		return s.Format(time.RFC3339Nano), true
+	case bool:
+		if s {
+			return "1", true
+		}
+		return "0", true
+	}
+	return "", false
+}
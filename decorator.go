@@ -0,0 +1,172 @@
+package pouch
+
+import (
+	"context"
+	"errors"
+)
+
+// decorator is the base of the Pouches and Queries in this package that
+// wrap another one (i.e. TenantPouch): it passes every operation on to
+// the wrapped Query, and wraps every Query built from it again, so the
+// decorators built on it only implement the operations they change.
+type decorator struct {
+	inner Query
+	wrap  func(Query) Query
+	// within wraps the Pouch of a transaction as the decorator wraps its
+	// own, for decorators that hold on to their Pouch; the others have
+	// the transaction's blank Query wrapped.
+	within func(Pouch) Query
+}
+
+// blank returns a blank Query for the given Pouch, see the package docs.
+func blank(p Pouch) Query {
+	if q, ok := p.(Query); ok {
+		return q
+	}
+	return p.Offset(0)
+}
+
+func (d decorator) GroupBy(spec string) Query {
+	return d.wrap(d.inner.GroupBy(spec))
+}
+
+func (d decorator) OrderBy(spec string) Query {
+	return d.wrap(d.inner.OrderBy(spec))
+}
+
+func (d decorator) Where(frag string, vals ...interface{}) Query {
+	return d.wrap(d.inner.Where(frag, vals...))
+}
+
+func (d decorator) Limit(lim int) Query {
+	return d.wrap(d.inner.Limit(lim))
+}
+
+func (d decorator) Offset(off int) Query {
+	return d.wrap(d.inner.Offset(off))
+}
+
+func (d decorator) WithDeleted() Query {
+	return d.wrap(d.inner.WithDeleted())
+}
+
+func (d decorator) OnlyDeleted() Query {
+	return d.wrap(d.inner.OnlyDeleted())
+}
+
+func (d decorator) Preload(relations ...string) Query {
+	return d.wrap(d.inner.Preload(relations...))
+}
+
+func (d decorator) After(c Cursor) Query {
+	return d.wrap(d.inner.After(c))
+}
+
+func (d decorator) Before(c Cursor) Query {
+	return d.wrap(d.inner.Before(c))
+}
+
+// Scopes applies the scopes to the decorated Query, so that the criterions
+// they add go through the decorator.
+func (d decorator) Scopes(scopes ...Scope) Query {
+	var q = d.wrap(d.inner)
+	for _, scope := range scopes {
+		q = scope(q)
+	}
+	return q
+}
+
+func (d decorator) Unscoped() Query {
+	return d.wrap(d.inner.Unscoped())
+}
+
+func (d decorator) WithContext(ctx context.Context) Query {
+	return d.wrap(d.inner.WithContext(ctx))
+}
+
+func (d decorator) Context() context.Context {
+	return d.inner.Context()
+}
+
+func (d decorator) AllowUnconstrained() Query {
+	return d.wrap(d.inner.AllowUnconstrained())
+}
+
+func (d decorator) Clone() Query {
+	return d.wrap(d.inner.Clone())
+}
+
+// RunInTx runs the transaction on the decorated Query, if it's
+// Transactional, handing fn the transaction's Pouch decorated again.
+func (d decorator) RunInTx(ctx context.Context, fn func(p Pouch) error) error {
+	t, ok := d.inner.(Transactional)
+	if !ok {
+		return errors.New("pouch does not support transactions")
+	}
+	return t.RunInTx(ctx, func(p Pouch) error {
+		if d.within != nil {
+			return fn(d.within(p))
+		}
+		return fn(d.wrap(blank(p)))
+	})
+}
+
+func (d decorator) Find(i Findable) error {
+	return d.inner.Find(i)
+}
+
+func (d decorator) FindAll(fs []Findable) error {
+	return d.inner.FindAll(fs)
+}
+
+func (d decorator) FindEntities(template Findable, res *[]Findable) error {
+	return d.inner.FindEntities(template, res)
+}
+
+func (d decorator) FindPage(template Findable, res *[]Findable) (Cursor, Cursor, error) {
+	return d.inner.FindPage(template, res)
+}
+
+func (d decorator) Create(c Createable) error {
+	return d.inner.Create(c)
+}
+
+func (d decorator) CreateAll(cs []Createable) error {
+	return d.inner.CreateAll(cs)
+}
+
+func (d decorator) Update(u Updateable) error {
+	return d.inner.Update(u)
+}
+
+func (d decorator) UpdateColumns(u Updateable, cols ...string) error {
+	return d.inner.UpdateColumns(u, cols...)
+}
+
+func (d decorator) UpdateAll(us []Updateable) error {
+	return d.inner.UpdateAll(us)
+}
+
+func (d decorator) Delete(del Deleteable) error {
+	return d.inner.Delete(del)
+}
+
+func (d decorator) DeleteAll(ds []Deleteable) error {
+	return d.inner.DeleteAll(ds)
+}
+
+func (d decorator) HardDelete(del Deleteable) error {
+	return d.inner.HardDelete(del)
+}
+
+func (d decorator) Restore(sd SoftDeleteable) error {
+	return d.inner.Restore(sd)
+}
+
+func (d decorator) UpdateWhere(t Tableable, assignments map[string]interface{}) (int64, error) {
+	return d.inner.UpdateWhere(t, assignments)
+}
+
+func (d decorator) DeleteWhere(t Tableable) (int64, error) {
+	return d.inner.DeleteWhere(t)
+}
@@ -20,7 +20,8 @@
 // Pouch. This can be used to:
 //   - retrieve a certain entity without previously interactive with it
 //   - implementing server side pagination, agnostic of the backing storage
-//   - read only views, write only views, delete only views (permissions)
+//   - read only views, write only views, delete only views (permissions,
+//     see ReadOnly, WriteOnly, DeleteOnly and WithPolicy)
 //   - add criteria that are difficult to express (cleanly) in Go
 //
 // NOTE
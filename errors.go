@@ -0,0 +1,49 @@
+package pouch
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// ErrUnconstrained is returned when a bulk update or delete would
+// affect every entity in a table and the Query did not allow it.
+var ErrUnconstrained = errors.New("refusing to update or delete an entire table without constraints")
+
+// ErrWrongTenant is returned by a TenantPouch for entities that belong
+// to another tenant, or that don't exist for its tenant.
+var ErrWrongTenant = errors.New("entity belongs to another tenant")
+
+// A ConflictError is returned when Versioned entities could not be
+// updated because they were modified in the backing Storage since
+// they were retrieved.
+type ConflictError struct {
+	// Conflicts holds every entity that was not updated.
+	Conflicts []Updateable
+}
+
+func (c *ConflictError) Error() string {
+	var conflicts = make([]string, len(c.Conflicts))
+	for i, u := range c.Conflicts {
+		_, vals := u.IdentifiableFields()
+		conflicts[i] = fmt.Sprintf("%s %v", u.Table(), vals)
+	}
+	return "version conflict updating: " + strings.Join(conflicts, ", ")
+}
+
+// A NotFoundError is returned by strict Storage when entities that
+// were to be updated or deleted did not match anything in the backing
+// Storage.
+type NotFoundError struct {
+	// Missing holds every entity that did not match anything.
+	Missing []Deleteable
+}
+
+func (n *NotFoundError) Error() string {
+	var missing = make([]string, len(n.Missing))
+	for i, d := range n.Missing {
+		_, vals := d.IdentifiableFields()
+		missing[i] = fmt.Sprintf("%s %v", d.Table(), vals)
+	}
+	return "entities not found: " + strings.Join(missing, ", ")
+}
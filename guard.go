@@ -0,0 +1,362 @@
+package pouch
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+)
+
+// ErrCircuitOpen is returned by a Guarded pouch for operations it
+// refuses to run while its circuit breaker is open.
+var ErrCircuitOpen = errors.New("circuit breaker is open")
+
+// An OverloadedError is returned by a Guarded pouch for operations that
+// waited too long for their turn to run.
+type OverloadedError struct {
+	// Table is the table whose operations were at their limit, or empty
+	// if operations are limited globally.
+	Table  string
+	Waited time.Duration
+	// Err is the error of the context of the operation, if it was done
+	// before its turn came.
+	Err error
+}
+
+func (o *OverloadedError) Error() string {
+	var msg = "too many operations in flight"
+	if o.Table != "" {
+		msg += " on " + o.Table
+	}
+	msg += ", shed after waiting " + o.Waited.String()
+	if o.Err != nil {
+		msg += ": " + o.Err.Error()
+	}
+	return msg
+}
+
+func (o *OverloadedError) Unwrap() error { return o.Err }
+
+// A GuardPolicy decides how many operations a Guarded pouch runs at once
+// and when it stops running them altogether.
+type GuardPolicy struct {
+	// MaxConcurrent is how many operations run at once at most, globally
+	// or for each table if PerTable is set; zero means no limit.
+	MaxConcurrent int
+	PerTable      bool
+	// QueueTimeout is how long an operation waits for its turn before
+	// it's shed with an *OverloadedError; zero means it waits until its
+	// context is done.
+	QueueTimeout time.Duration
+
+	// FailureThreshold is how many operations in a row fail before the
+	// circuit breaker opens, zero disables it. While it's open operations
+	// fail with ErrCircuitOpen, until OpenTimeout has passed and one
+	// operation is let through as a probe: the breaker closes if it
+	// succeeds, and opens again if it fails.
+	FailureThreshold int
+	OpenTimeout      time.Duration
+	// Failure reports whether an error counts as a failure of the backing
+	// Storage, by default those which ErrorClass calls "other" or
+	// "timeout". Errors that are the caller's (i.e. entities not found)
+	// do not count.
+	Failure func(err error) bool
+	// Now tells the time the circuit breaker goes by, time.Now is used
+	// if it's nil; it's meant for tests.
+	Now func() time.Time
+}
+
+func (p GuardPolicy) failure(err error) bool {
+	if err == nil {
+		return false
+	}
+	if p.Failure != nil {
+		return p.Failure(err)
+	}
+	switch ErrorClass(err) {
+	case "other", "timeout":
+		return true
+	}
+	return false
+}
+
+// Guarded returns a view of the given Pouch which limits how many of the
+// operations that read or write entities run at once, and fails fast
+// with its circuit breaker once the backing Storage keeps failing, as
+// the policy says. Operations queue up for their turn with the context
+// of their Query (see Queryable.WithContext). The view is Transactional,
+// running transactions on the Pouch (and the views it wraps) if it is,
+// each as one operation.
+func Guarded(p Pouch, policy GuardPolicy) Pouch {
+	return newGuardedQuery(blank(p), &guard{
+		policy:  policy,
+		slots:   make(map[string]chan struct{}),
+		breaker: &breaker{threshold: policy.FailureThreshold, timeout: policy.OpenTimeout, now: policy.Now},
+	})
+}
+
+// guard holds the state of a Guarded pouch, which all its queries share.
+type guard struct {
+	policy GuardPolicy
+
+	mu sync.Mutex
+	// tokens of the operations in flight, by table ("" if global)
+	slots   map[string]chan struct{}
+	breaker *breaker
+}
+
+// acquire waits for the turn of an operation on the given table, and
+// returns the func to call once it ran.
+func (g *guard) acquire(ctx context.Context, table string) (func(), error) {
+	if g.policy.MaxConcurrent <= 0 {
+		return func() {}, nil
+	}
+	if !g.policy.PerTable {
+		table = ""
+	}
+	g.mu.Lock()
+	slots, ok := g.slots[table]
+	if !ok {
+		slots = make(chan struct{}, g.policy.MaxConcurrent)
+		g.slots[table] = slots
+	}
+	g.mu.Unlock()
+
+	release := func() { <-slots }
+	select {
+	case slots <- struct{}{}:
+		return release, nil
+	default:
+	}
+
+	var (
+		start   = time.Now()
+		timeout <-chan time.Time
+	)
+	if g.policy.QueueTimeout > 0 {
+		timer := time.NewTimer(g.policy.QueueTimeout)
+		defer timer.Stop()
+		timeout = timer.C
+	}
+	select {
+	case slots <- struct{}{}:
+		return release, nil
+	case <-timeout:
+		return nil, &OverloadedError{Table: table, Waited: time.Since(start)}
+	case <-ctx.Done():
+		return nil, &OverloadedError{Table: table, Waited: time.Since(start), Err: ctx.Err()}
+	}
+}
+
+// run runs an operation on the given table, if the breaker lets it and
+// once its turn has come.
+func (g *guard) run(ctx context.Context, table string, f func() error) error {
+	probe, err := g.breaker.allow()
+	if err != nil {
+		return err
+	}
+	release, err := g.acquire(ctx, table)
+	if err != nil {
+		g.breaker.done(probe, false, false)
+		return err
+	}
+	defer release()
+
+	err = f()
+	g.breaker.done(probe, true, g.policy.failure(err))
+	return err
+}
+
+// the states of a circuit breaker
+const (
+	breakerClosed = iota
+	breakerOpen
+	breakerHalfOpen
+)
+
+// breaker is a circuit breaker, which opens after threshold operations
+// in a row failed and lets a probe through once it has been open for
+// the timeout.
+type breaker struct {
+	threshold int
+	timeout   time.Duration
+	now       func() time.Time
+
+	mu       sync.Mutex
+	state    int
+	failures int
+	opened   time.Time
+	// whether the probe of the half open breaker is in flight
+	probing bool
+}
+
+func (b *breaker) clock() time.Time {
+	if b.now != nil {
+		return b.now()
+	}
+	return time.Now()
+}
+
+// allow returns whether an operation may run, and whether it's the
+// probe of the half open breaker.
+func (b *breaker) allow() (probe bool, err error) {
+	if b.threshold <= 0 {
+		return false, nil
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	switch b.state {
+	case breakerOpen:
+		if b.clock().Sub(b.opened) < b.timeout {
+			return false, ErrCircuitOpen
+		}
+		b.state = breakerHalfOpen
+		fallthrough
+	case breakerHalfOpen:
+		if b.probing {
+			return false, ErrCircuitOpen
+		}
+		b.probing = true
+		return true, nil
+	}
+	return false, nil
+}
+
+// done records how an operation the breaker allowed went: whether it
+// ran at all, and whether it failed.
+func (b *breaker) done(probe, ran, failed bool) {
+	if b.threshold <= 0 {
+		return
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if probe {
+		b.probing = false
+		switch {
+		case !ran:
+			// the next operation probes instead
+		case failed:
+			b.state, b.opened = breakerOpen, b.clock()
+		default:
+			b.state, b.failures = breakerClosed, 0
+		}
+		return
+	}
+	if !ran || b.state != breakerClosed {
+		return
+	}
+	if !failed {
+		b.failures = 0
+		return
+	}
+	b.failures++
+	if b.failures >= b.threshold {
+		b.state, b.opened = breakerOpen, b.clock()
+	}
+}
+
+type guardedQuery struct {
+	decorator
+	guard *guard
+}
+
+func newGuardedQuery(inner Query, g *guard) *guardedQuery {
+	return &guardedQuery{
+		decorator: decorator{
+			inner: inner,
+			wrap: func(q Query) Query {
+				return newGuardedQuery(q, g)
+			},
+		},
+		guard: g,
+	}
+}
+
+func (s *guardedQuery) run(table string, f func() error) error {
+	return s.guard.run(s.inner.Context(), table, f)
+}
+
+// RunInTx runs the transaction as one operation, on the wrapped Query:
+// the transaction's own operations aren't guarded, as they would wait
+// for the turn the transaction holds.
+func (s *guardedQuery) RunInTx(ctx context.Context, fn func(p Pouch) error) error {
+	t, ok := s.inner.(Transactional)
+	if !ok {
+		return errors.New("pouch does not support transactions")
+	}
+	return s.guard.run(ctx, "", func() error {
+		return t.RunInTx(ctx, fn)
+	})
+}
+
+func (s *guardedQuery) Find(i Findable) error {
+	return s.run(i.Table(), func() error { return s.inner.Find(i) })
+}
+
+func (s *guardedQuery) FindAll(fs []Findable) error {
+	return s.run(firstTable(fs), func() error { return s.inner.FindAll(fs) })
+}
+
+func (s *guardedQuery) FindEntities(template Findable, res *[]Findable) error {
+	return s.run(template.Table(), func() error { return s.inner.FindEntities(template, res) })
+}
+
+func (s *guardedQuery) FindPage(template Findable, res *[]Findable) (next, prev Cursor, err error) {
+	err = s.run(template.Table(), func() error {
+		next, prev, err = s.inner.FindPage(template, res)
+		return err
+	})
+	return next, prev, err
+}
+
+func (s *guardedQuery) Create(c Createable) error {
+	return s.run(c.Table(), func() error { return s.inner.Create(c) })
+}
+
+func (s *guardedQuery) CreateAll(cs []Createable) error {
+	return s.run(firstTable(cs), func() error { return s.inner.CreateAll(cs) })
+}
+
+func (s *guardedQuery) Update(u Updateable) error {
+	return s.run(u.Table(), func() error { return s.inner.Update(u) })
+}
+
+func (s *guardedQuery) UpdateColumns(u Updateable, cols ...string) error {
+	return s.run(u.Table(), func() error { return s.inner.UpdateColumns(u, cols...) })
+}
+
+func (s *guardedQuery) UpdateAll(us []Updateable) error {
+	return s.run(firstTable(us), func() error { return s.inner.UpdateAll(us) })
+}
+
+func (s *guardedQuery) Delete(d Deleteable) error {
+	return s.run(d.Table(), func() error { return s.inner.Delete(d) })
+}
+
+func (s *guardedQuery) DeleteAll(ds []Deleteable) error {
+	return s.run(firstTable(ds), func() error { return s.inner.DeleteAll(ds) })
+}
+
+func (s *guardedQuery) HardDelete(d Deleteable) error {
+	return s.run(d.Table(), func() error { return s.inner.HardDelete(d) })
+}
+
+func (s *guardedQuery) Restore(sd SoftDeleteable) error {
+	return s.run(sd.Table(), func() error { return s.inner.Restore(sd) })
+}
+
+func (s *guardedQuery) UpdateWhere(t Tableable, assignments map[string]interface{}) (n int64, err error) {
+	err = s.run(t.Table(), func() error {
+		n, err = s.inner.UpdateWhere(t, assignments)
+		return err
+	})
+	return n, err
+}
+
+func (s *guardedQuery) DeleteWhere(t Tableable) (n int64, err error) {
+	err = s.run(t.Table(), func() error {
+		n, err = s.inner.DeleteWhere(t)
+		return err
+	})
+	return n, err
+}
@@ -0,0 +1,149 @@
+package impl
+
+import (
+	"context"
+	"database/sql/driver"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/ttacon/pouch"
+)
+
+type auditLog []*pouch.AuditRecord
+
+func (a *auditLog) Record(ctx context.Context, r *pouch.AuditRecord) error {
+	*a = append(*a, r)
+	return nil
+}
+
+func Test_auditPouch(t *testing.T) {
+	db, fake := newFakeDB()
+	fake.columns = []string{"ID", "TenantID", "Body"}
+	fake.rows = [][]driver.Value{{int64(3), int64(7), "before"}}
+	fake.lastID = 3
+
+	var log auditLog
+	p := pouch.AuditPouch(SQLPouch(db), &log)
+	q := p.WithContext(pouch.WithCaller(context.Background(), "ada"))
+
+	if err := q.Create(&Note{TenantID: 7, Body: "new"}); err != nil {
+		t.Fatal("err should have been nil, was: ", err)
+	}
+	if err := q.Update(&Note{ID: 3, TenantID: 7, Body: "after"}); err != nil {
+		t.Fatal("err should have been nil, was: ", err)
+	}
+	if err := q.Delete(&Note{ID: 3}); err != nil {
+		t.Fatal("err should have been nil, was: ", err)
+	}
+	if len(log) != 3 {
+		t.Fatal("expected a record per write, had: ", len(log))
+	}
+
+	created, updated, deleted := log[0], log[1], log[2]
+	if created.Op != pouch.OpCreate || created.Before != nil || created.Identity["ID"] != 3 {
+		t.Error("unexpected record of a create: ", created)
+	}
+	if updated.Entity != "Note" || updated.Actor != "ada" || updated.At.IsZero() {
+		t.Error("records should say what was written to, by whom and when: ", updated)
+	}
+	if updated.Before["Body"] != "before" || updated.After["Body"] != "after" {
+		t.Error("records of updates should hold the values before and after: ", updated.Before, updated.After)
+	}
+	if deleted.Op != pouch.OpDelete || deleted.After != nil || deleted.Before["Body"] != "before" {
+		t.Error("unexpected record of a delete: ", deleted)
+	}
+}
+
+func Test_auditWrittenColumns(t *testing.T) {
+	db, fake := newFakeDB()
+	fake.columns = []string{"ID", "Name", "Color"}
+	fake.rows = [][]driver.Value{{int64(1), "rose", "red"}}
+
+	var log auditLog
+	p := pouch.AuditPouch(SQLPouch(db), &log)
+
+	var flower = Flower{ID: 1}
+	if err := p.Find(&flower); err != nil {
+		t.Fatal("err should have been nil, was: ", err)
+	}
+	flower.Color = "white"
+	if err := p.Update(&flower); err != nil {
+		t.Fatal("err should have been nil, was: ", err)
+	}
+	if len(log) != 1 || len(log[0].After) != 1 || log[0].After["Color"] != "white" {
+		t.Fatal("only the changed columns should be recorded after, had: ", log)
+	}
+	if log[0].Before["Name"] != "rose" {
+		t.Error("the stored columns should still be recorded before, had: ", log[0].Before)
+	}
+
+	var ran = len(fake.all())
+	if err := p.Update(&flower); err != nil {
+		t.Fatal("err should have been nil, was: ", err)
+	}
+	if len(log) != 1 || len(fake.all()) != ran {
+		t.Error("updates that write nothing should not be recorded, had: ", log)
+	}
+
+	if err := p.UpdateColumns(&Flower{ID: 1, Name: "tulip"}, "Name"); err != nil {
+		t.Fatal("err should have been nil, was: ", err)
+	}
+	if len(log) != 2 || len(log[1].After) != 1 || log[1].After["Name"] != "tulip" {
+		t.Error("only the updated columns should be recorded after, had: ", log[1].After)
+	}
+}
+
+// label is an entity that isn't a pointer, nor comparable.
+type label struct {
+	ID   int
+	Tags []string
+}
+
+func (l label) IdentifiableFields() ([]string, []interface{}) {
+	return []string{"ID"}, []interface{}{l.ID}
+}
+
+func (l label) Table() string { return "Label" }
+
+func Test_auditPartialWrites(t *testing.T) {
+	d := NewDynamicPouch(nil, Strict())
+	d.SetDleteAll(func(ds []pouch.Deleteable, i interface{}) error {
+		return &pouch.NotFoundError{Missing: ds[1:]}
+	})
+	var log auditLog
+	p := pouch.AuditPouch(d, &log)
+
+	var labels = []pouch.Deleteable{label{ID: 1, Tags: []string{"a"}}, label{ID: 2, Tags: []string{"b"}}}
+	var notFound *pouch.NotFoundError
+	if err := p.DeleteAll(labels); !errors.As(err, &notFound) {
+		t.Fatal("err should have been a *pouch.NotFoundError, was: ", err)
+	}
+	if len(log) != 1 || log[0].Identity["ID"] != 1 {
+		t.Error("only the entities that were written to should be recorded, had: ", log)
+	}
+}
+
+func Test_pouchAuditSink(t *testing.T) {
+	db, fake := newFakeDB()
+	sink := pouch.PouchAuditSink(SQLPouch(db), "audit_log")
+
+	err := sink.Record(context.Background(), &pouch.AuditRecord{
+		Entity:   "Note",
+		Op:       pouch.OpUpdate,
+		Identity: map[string]interface{}{"ID": 3},
+		After:    map[string]interface{}{"Body": "after"},
+		Actor:    42,
+	})
+	if err != nil {
+		t.Fatal("err should have been nil, was: ", err)
+	}
+
+	stmt := fake.last()
+	if !strings.HasPrefix(stmt.query, "insert into audit_log") {
+		t.Fatal("records should be stored through the pouch, ran: ", stmt.query)
+	}
+	if len(stmt.args) != 7 || stmt.args[1] != "update" || stmt.args[2] != `{"ID":3}` || stmt.args[5] != "42" {
+		t.Error("unexpected values stored for a record: ", stmt.args)
+	}
+}
@@ -0,0 +1,167 @@
+package impl
+
+import (
+	"context"
+	"database/sql/driver"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/ttacon/pouch"
+)
+
+var errNotYours = errors.New("not your note")
+
+// ownNotes only lets callers at their own notes, going by TenantID.
+var ownNotes = pouch.AuthorizerFunc(func(ctx context.Context, op pouch.Operation, e pouch.Tableable) error {
+	caller, ok := pouch.CallerFrom(ctx)
+	if !ok {
+		return errors.New("no caller")
+	}
+	if n, ok := e.(*Note); ok && op != pouch.OpCreate && n.TenantID != caller {
+		return errNotYours
+	}
+	return nil
+})
+
+func Test_authorizer(t *testing.T) {
+	db, fake := newFakeDB()
+	fake.respond = func(query string, args []driver.Value) ([]string, [][]driver.Value) {
+		var notes = [][]driver.Value{
+			{int64(1), int64(7), "mine"},
+			{int64(2), int64(8), "theirs"},
+		}
+		// notes are found by their ID, if one is given
+		for _, arg := range args {
+			for _, n := range notes {
+				if arg == n[0] {
+					return []string{"ID", "TenantID", "Body"}, [][]driver.Value{n}
+				}
+			}
+		}
+		return []string{"ID", "TenantID", "Body"}, notes
+	}
+	p := pouch.Authorized(SQLPouch(db), ownNotes)
+	ctx := pouch.WithCaller(context.Background(), 7)
+
+	var notes []pouch.Findable
+	if err := p.WithContext(ctx).FindEntities(&Note{}, &notes); err != nil {
+		t.Fatal("err should have been nil, was: ", err)
+	}
+	if len(notes) != 1 || notes[0].(*Note).Body != "mine" {
+		t.Error("notes the caller may not read should be left out, found: ", notes)
+	}
+
+	if err := p.Offset(0).FindEntities(&Note{}, &notes); err != nil {
+		t.Fatal("err should have been nil, was: ", err)
+	}
+	if len(notes) != 1 {
+		t.Error("without a caller nothing should be readable, found: ", len(notes)-1)
+	}
+
+	var ran = len(fake.all())
+	if err := p.WithContext(ctx).Update(&Note{ID: 2, TenantID: 8}); err != errNotYours {
+		t.Error("updating someone else's note should be refused, was: ", err)
+	}
+	// the note claims to be the caller's, but isn't stored as theirs
+	if err := p.WithContext(ctx).Update(&Note{ID: 2, TenantID: 7, Body: "forged"}); err != errNotYours {
+		t.Error("updating someone else's note by forging its owner should be refused, was: ", err)
+	}
+	if err := p.WithContext(ctx).Delete(&Note{ID: 2, TenantID: 7}); err != errNotYours {
+		t.Error("deleting someone else's note by forging its owner should be refused, was: ", err)
+	}
+	for _, stmt := range fake.all()[ran:] {
+		if !strings.HasPrefix(stmt.query, "select") {
+			t.Error("refused writes should not reach the database, ran: ", stmt.query)
+		}
+	}
+
+	var theirs = &Note{ID: 2}
+	if err := p.WithContext(ctx).Find(theirs); err != errNotYours {
+		t.Error("finding someone else's note should be refused, was: ", err)
+	}
+	if theirs.Body != "" || theirs.TenantID != 0 {
+		t.Error("a refused Find should leave the entity as it was, was: ", theirs)
+	}
+	var found = []pouch.Findable{&Note{ID: 1}, &Note{ID: 2}}
+	if err := p.WithContext(ctx).FindAll(found); err != errNotYours {
+		t.Error("finding someone else's note should be refused, was: ", err)
+	}
+	if found[0].(*Note).Body != "" {
+		t.Error("a refused FindAll should leave the entities as they were, was: ", found[0])
+	}
+	var mine = &Note{ID: 1}
+	if err := p.WithContext(ctx).Find(mine); err != nil || mine.Body != "mine" {
+		t.Error("finding one's own note should fill it in, was: ", mine, err)
+	}
+	if err := p.WithContext(ctx).Where("ID = ?", 1).Update(&Note{ID: 1, TenantID: 7}); err != nil {
+		t.Error("updating one's own note should be allowed, was: ", err)
+	}
+}
+
+// ownReplies only lets callers at their own notes and replies, and may
+// update their own notes in bulk.
+type ownReplies struct{}
+
+func (ownReplies) Authorize(ctx context.Context, op pouch.Operation, e pouch.Tableable) error {
+	caller, _ := pouch.CallerFrom(ctx)
+	if r, ok := e.(*Reply); ok && r.TenantID != caller {
+		return errNotYours
+	}
+	return ownNotes(ctx, op, e)
+}
+
+func (ownReplies) AuthorizeBulk(ctx context.Context, op pouch.Operation, e pouch.Tableable) error {
+	if op != pouch.OpUpdate {
+		return errNotYours
+	}
+	return nil
+}
+
+func Test_authorizerPreload(t *testing.T) {
+	db, fake := newFakeDB()
+	fake.respond = func(query string, args []driver.Value) ([]string, [][]driver.Value) {
+		if strings.Contains(query, "from Reply") {
+			return []string{"ID", "TenantID", "NoteID"}, [][]driver.Value{
+				{int64(5), int64(7), int64(3)}, {int64(6), int64(8), int64(3)},
+			}
+		}
+		return []string{"ID", "TenantID", "Body"}, [][]driver.Value{{int64(3), int64(7), "hi"}}
+	}
+	ctx := pouch.WithCaller(context.Background(), 7)
+	p := pouch.Authorized(SQLPouch(db), ownReplies{})
+
+	var notes []pouch.Findable
+	if err := p.WithContext(ctx).Preload("Replies").FindEntities(&Note{}, &notes); err != nil {
+		t.Fatal("err should have been nil, was: ", err)
+	}
+	if len(notes) != 1 || len(notes[0].(*Note).Replies) != 1 || notes[0].(*Note).Replies[0].ID != 5 {
+		t.Error("only related entities the caller may read should have been attached, found: ", notes)
+	}
+}
+
+func Test_authorizerBulk(t *testing.T) {
+	db, fake := newFakeDB()
+	ctx := pouch.WithCaller(context.Background(), 7)
+
+	var ran = len(fake.all())
+	p := pouch.Authorized(SQLPouch(db), ownNotes)
+	var perm *pouch.PermissionError
+	if _, err := p.WithContext(ctx).Where("TenantID = ?", 7).UpdateWhere(&Note{}, map[string]interface{}{"Body": "x"}); !errors.As(err, &perm) {
+		t.Error("updating in bulk should be refused unless allowed, was: ", err)
+	}
+	if _, err := p.WithContext(ctx).Where("TenantID = ?", 7).DeleteWhere(&Note{}); !errors.As(err, &perm) {
+		t.Error("deleting in bulk should be refused unless allowed, was: ", err)
+	}
+	if len(fake.all()) != ran {
+		t.Error("refused bulk writes should not reach the database, ran: ", fake.all()[ran:])
+	}
+
+	p = pouch.Authorized(SQLPouch(db), ownReplies{})
+	if _, err := p.WithContext(ctx).Where("TenantID = ?", 7).UpdateWhere(&Note{}, map[string]interface{}{"Body": "x"}); err != nil {
+		t.Error("updating in bulk should be allowed by a BulkAuthorizer, was: ", err)
+	}
+	if _, err := p.WithContext(ctx).Where("TenantID = ?", 7).DeleteWhere(&Note{}); err != errNotYours {
+		t.Error("deleting in bulk should be refused by the BulkAuthorizer, was: ", err)
+	}
+}
@@ -1,33 +1,43 @@
 package impl
 
 import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
 	"errors"
+	"reflect"
 
 	"github.com/ttacon/pouch"
 )
 
 type dynamicPouch struct {
-	l      Logger
+	l      *pouchLogger
 	backer interface{}
+	opts   options
 
 	// the secret aioli:
-	find      func(pouch.Findable, interface{}) error
-	findAll   func([]pouch.Findable, interface{}) error
-	create    func(pouch.Createable, interface{}) error
-	createAll func([]pouch.Createable, interface{}) error
-	update    func(pouch.Updateable, interface{}) error
-	updateAll func([]pouch.Updateable, interface{}) error
-	dlete     func(pouch.Deleteable, interface{}) error
-	dleteAll  func([]pouch.Deleteable, interface{}) error
+	find       func(pouch.Findable, interface{}) error
+	findAll    func([]pouch.Findable, interface{}) error
+	findEnts   func(pouch.Findable, *[]pouch.Findable, interface{}) error
+	create     func(pouch.Createable, interface{}) error
+	createAll  func([]pouch.Createable, interface{}) error
+	update     func(pouch.Updateable, interface{}) error
+	updateCols func(pouch.Updateable, []string, interface{}) error
+	updateAll  func([]pouch.Updateable, interface{}) error
+	dlete      func(pouch.Deleteable, interface{}) error
+	dleteAll   func([]pouch.Deleteable, interface{}) error
 }
 
 type DynamicPouch interface {
 	pouch.Pouch
+	Loggable
 	SetFind(func(pouch.Findable, interface{}) error)
 	SetFindAll(func([]pouch.Findable, interface{}) error)
+	SetFindEntities(func(pouch.Findable, *[]pouch.Findable, interface{}) error)
 	SetCreate(func(pouch.Createable, interface{}) error)
 	SetCreateAll(func([]pouch.Createable, interface{}) error)
 	SetUpdate(func(pouch.Updateable, interface{}) error)
+	SetUpdateColumns(func(pouch.Updateable, []string, interface{}) error)
 	SetUpdateAll(func([]pouch.Updateable, interface{}) error)
 	SetDlete(func(pouch.Deleteable, interface{}) error)
 	SetDleteAll(func([]pouch.Deleteable, interface{}) error)
@@ -37,115 +47,155 @@ type DynamicQuery interface {
 	pouch.Query
 	SetFind(func(pouch.Findable, interface{}) error)
 	SetFindAll(func([]pouch.Findable, interface{}) error)
+	SetFindEntities(func(pouch.Findable, *[]pouch.Findable, interface{}) error)
 	SetCreate(func(pouch.Createable, interface{}) error)
 	SetCreateAll(func([]pouch.Createable, interface{}) error)
 	SetUpdate(func(pouch.Updateable, interface{}) error)
+	SetUpdateColumns(func(pouch.Updateable, []string, interface{}) error)
 	SetUpdateAll(func([]pouch.Updateable, interface{}) error)
 	SetDlete(func(pouch.Deleteable, interface{}) error)
 	SetDleteAll(func([]pouch.Deleteable, interface{}) error)
 }
 
-func NewDynamicPouch(backer interface{}) DynamicPouch {
+// NewDynamicPouch returns a pouch backed by the given data, whose
+// operations are all defined by the Set* functions. Update and Delete
+// functions signal that an entity does not exist by returning a
+// *pouch.NotFoundError, which is only passed on by a Strict pouch.
+//
+// The Find functions find entities whether they are soft deleted or
+// not, the pouch then hides them from reads as WithDeleted and
+// OnlyDeleted say, going by their deleted at field. Soft deleting is
+// up to the Delete functions, restoring goes through UpdateColumns.
+//
+// As the pouch ignores the criterions of its queries, it refuses to find
+// or write entities of a table with DefaultScopes unless the query is
+// Unscoped, rather than silently skip them.
+func NewDynamicPouch(backer interface{}, opts ...Option) DynamicPouch {
+	o := newOptions(opts)
 	return &dynamicPouch{
-		l:      defaultLogger(),
+		l:      newPouchLogger(o),
 		backer: backer,
+		opts:   o,
 	}
 }
 
-func (s *dynamicPouch) GroupBy(spec string) pouch.Query {
+// SetLogger makes the pouch, and the queries built from it, log through
+// the given Logger from now on.
+func (s *dynamicPouch) SetLogger(l Logger) {
+	s.l.set(l)
+}
+
+// SetVerbosity sets the verbosity of the pouch's Logger.
+func (s *dynamicPouch) SetVerbosity(level VerbosityLevel) {
+	s.l.SetVerbosity(level)
+}
+
+// filter returns a blank query, with the same backer and functions as
+// the pouch, which every operation on the pouch goes through.
+func (s *dynamicPouch) filter() *dynamicFilter {
 	return &dynamicFilter{
-		groupBySpecs: []string{spec},
-		l:            s.l,
+		backer:     s.backer,
+		l:          s.l,
+		opts:       s.opts,
+		find:       s.find,
+		findAll:    s.findAll,
+		findEnts:   s.findEnts,
+		create:     s.create,
+		createAll:  s.createAll,
+		update:     s.update,
+		updateCols: s.updateCols,
+		updateAll:  s.updateAll,
+		dlete:      s.dlete,
+		dleteAll:   s.dleteAll,
 	}
 }
 
+func (s *dynamicPouch) GroupBy(spec string) pouch.Query {
+	return s.filter().GroupBy(spec)
+}
+
 func (s *dynamicPouch) OrderBy(spec string) pouch.Query {
-	return &dynamicFilter{
-		orderBySpecs: []string{spec},
-		l:            s.l,
-	}
+	return s.filter().OrderBy(spec)
 }
 
 func (s *dynamicPouch) Where(frag string, vals ...interface{}) pouch.Query {
-	return &dynamicFilter{
-		constraints: []constraintPair{
-			constraintPair{
-				frag: frag,
-				vals: vals,
-			},
-		},
-		l: s.l,
-	}
+	return s.filter().Where(frag, vals...)
 }
 
 func (s *dynamicPouch) Limit(lim int) pouch.Query {
-	return &dynamicFilter{
-		limit: lim,
-		l:     s.l,
-	}
+	return s.filter().Limit(lim)
 }
 
 func (s *dynamicPouch) Offset(off int) pouch.Query {
-	return &dynamicFilter{
-		offset: off,
-		l:      s.l,
-	}
+	return s.filter().Offset(off)
+}
+
+func (s *dynamicPouch) WithDeleted() pouch.Query {
+	return s.filter().WithDeleted()
+}
+
+func (s *dynamicPouch) OnlyDeleted() pouch.Query {
+	return s.filter().OnlyDeleted()
+}
+
+func (s *dynamicPouch) Preload(relations ...string) pouch.Query {
+	return s.filter().Preload(relations...)
+}
+
+func (s *dynamicPouch) After(c pouch.Cursor) pouch.Query {
+	return s.filter().After(c)
+}
+
+func (s *dynamicPouch) Before(c pouch.Cursor) pouch.Query {
+	return s.filter().Before(c)
+}
+
+func (s *dynamicPouch) Scopes(scopes ...pouch.Scope) pouch.Query {
+	return s.filter().Scopes(scopes...)
+}
+
+func (s *dynamicPouch) Unscoped() pouch.Query {
+	return s.filter().Unscoped()
+}
+
+func (s *dynamicPouch) WithContext(ctx context.Context) pouch.Query {
+	return s.filter().WithContext(ctx)
 }
 
 func (s *dynamicPouch) Find(i pouch.Findable) error {
-	if s.find == nil {
-		return errors.New("no Find function has been defined")
-	}
-	return s.find(i, s.backer)
+	return s.filter().Find(i)
 }
 
 func (s *dynamicPouch) FindAll(fs []pouch.Findable) error {
-	if s.findAll == nil {
-		return errors.New("no FindAll function has been defined")
-	}
-	return s.findAll(fs, s.backer)
+	return s.filter().FindAll(fs)
 }
 
 func (s *dynamicPouch) Create(i pouch.Createable) error {
-	if s.create == nil {
-		return errors.New("no Create function has been defined")
-	}
-	return s.create(i, s.backer)
+	return s.filter().Create(i)
 }
 
 func (s *dynamicPouch) CreateAll(cs []pouch.Createable) error {
-	if s.createAll == nil {
-		return errors.New("no CreateAll function has been defined")
-	}
-	return s.createAll(cs, s.backer)
+	return s.filter().CreateAll(cs)
 }
 
 func (s *dynamicPouch) Update(u pouch.Updateable) error {
-	if s.update == nil {
-		return errors.New("no Update function has been defined")
-	}
-	return s.update(u, s.backer)
+	return s.filter().Update(u)
+}
+
+func (s *dynamicPouch) UpdateColumns(u pouch.Updateable, cols ...string) error {
+	return s.filter().UpdateColumns(u, cols...)
 }
 
 func (s *dynamicPouch) UpdateAll(u []pouch.Updateable) error {
-	if s.updateAll == nil {
-		return errors.New("no UpdateAll function has been defined")
-	}
-	return s.updateAll(u, s.backer)
+	return s.filter().UpdateAll(u)
 }
 
 func (s *dynamicPouch) Delete(i pouch.Deleteable) error {
-	if s.dlete == nil {
-		return errors.New("no Delete function has been defined")
-	}
-	return s.dlete(i, s.backer)
+	return s.filter().Delete(i)
 }
 
 func (s *dynamicPouch) DeleteAll(ds []pouch.Deleteable) error {
-	if s.dleteAll == nil {
-		return errors.New("no DeleteAll function has been defined")
-	}
-	return s.dleteAll(ds, s.backer)
+	return s.filter().DeleteAll(ds)
 }
 
 ////////// SQL pouch.Query implementation //////////
@@ -156,84 +206,410 @@ type dynamicFilter struct {
 	constraints  []constraintPair
 	limit        int
 	offset       int
-	l            Logger
+	deleted      deletedScope
+	after        pouch.Cursor
+	before       pouch.Cursor
+	unscoped     bool
+	preloads     []string
+	ctx          context.Context
+	l            *pouchLogger
+	opts         options
 
 	// the secret aioli:
-	find      func(pouch.Findable, interface{}) error
-	findAll   func([]pouch.Findable, interface{}) error
-	create    func(pouch.Createable, interface{}) error
-	createAll func([]pouch.Createable, interface{}) error
-	update    func(pouch.Updateable, interface{}) error
-	updateAll func([]pouch.Updateable, interface{}) error
-	dlete     func(pouch.Deleteable, interface{}) error
-	dleteAll  func([]pouch.Deleteable, interface{}) error
-}
-
+	find       func(pouch.Findable, interface{}) error
+	findAll    func([]pouch.Findable, interface{}) error
+	findEnts   func(pouch.Findable, *[]pouch.Findable, interface{}) error
+	create     func(pouch.Createable, interface{}) error
+	createAll  func([]pouch.Createable, interface{}) error
+	update     func(pouch.Updateable, interface{}) error
+	updateCols func(pouch.Updateable, []string, interface{}) error
+	updateAll  func([]pouch.Updateable, interface{}) error
+	dlete      func(pouch.Deleteable, interface{}) error
+	dleteAll   func([]pouch.Deleteable, interface{}) error
+}
+
+// Find has the defined Find function find the entity, which is then
+// treated as missing if the query doesn't see it (see sees).
 func (s *dynamicFilter) Find(i pouch.Findable) error {
-	// TODO(ttaco): do it
+	if s.find == nil {
+		return errors.New("no Find function has been defined")
+	}
+	if err := s.scoped(i.Table()); err != nil {
+		return err
+	}
+	if err := s.find(i, s.backer); err != nil {
+		return err
+	}
+	if !s.sees(i) {
+		return sql.ErrNoRows
+	}
 	return nil
 }
 
 func (s *dynamicFilter) FindAll(fs []pouch.Findable) error {
-	// TODO(ttaco): do it
+	if s.findAll == nil {
+		return errors.New("no FindAll function has been defined")
+	}
+	if err := s.scoped(firstTable(fs)); err != nil {
+		return err
+	}
+	if err := s.findAll(fs, s.backer); err != nil {
+		return err
+	}
+	for _, f := range fs {
+		if !s.sees(f) {
+			return sql.ErrNoRows
+		}
+	}
 	return nil
 }
 
+// sees reports whether the query sees the given entity, going by whether
+// it's soft deleted: the defined functions find entities regardless, so
+// the query hides the soft deleted ones (or the others) itself.
+func (s *dynamicFilter) sees(f pouch.Findable) bool {
+	deleted, ok := softDeleted(f)
+	if !ok {
+		return true
+	}
+	switch s.deleted {
+	case excludeDeleted:
+		return !deleted
+	case onlyDeleted:
+		return deleted
+	}
+	return true
+}
+
+// softDeleted reports whether the given entity is soft deleted, going by
+// its deleted at field, if it is SoftDeleteable.
+func softDeleted(f pouch.Findable) (deleted, ok bool) {
+	sd, ok := f.(pouch.SoftDeleteable)
+	if !ok || len(sd.DeletedAtColumn()) == 0 {
+		return false, false
+	}
+	fields := f.GetFieldsFor([]string{sd.DeletedAtColumn()})
+	if len(fields) == 0 || fields[0] == nil {
+		return false, false
+	}
+
+	v := reflect.Indirect(reflect.ValueOf(fields[0]))
+	if valuer, ok := v.Interface().(driver.Valuer); ok {
+		val, err := valuer.Value()
+		return err == nil && val != nil, true
+	}
+	switch v.Kind() {
+	case reflect.Ptr, reflect.Interface:
+		return !v.IsNil(), true
+	}
+	return !v.IsZero(), true
+}
+
 func (s *dynamicFilter) Create(i pouch.Createable) error {
+	if s.create == nil {
+		return errors.New("no Create function has been defined")
+	}
 	return s.create(i, s.backer)
 }
 
 func (s *dynamicFilter) CreateAll(cs []pouch.Createable) error {
+	if s.createAll == nil {
+		return errors.New("no CreateAll function has been defined")
+	}
 	return s.createAll(cs, s.backer)
 }
 
 func (s *dynamicFilter) Update(u pouch.Updateable) error {
-	return s.update(u, s.backer)
+	if s.update == nil {
+		return errors.New("no Update function has been defined")
+	}
+	if err := s.scoped(u.Table()); err != nil {
+		return err
+	}
+	return s.notFound(s.update(u, s.backer))
+}
+
+// UpdateColumns hands the defined UpdateColumns function the entity, and
+// the columns of it to update.
+func (s *dynamicFilter) UpdateColumns(u pouch.Updateable, cols ...string) error {
+	if len(cols) == 0 {
+		return errors.New("no columns to update")
+	}
+	if s.updateCols == nil {
+		return errors.New("no UpdateColumns function has been defined")
+	}
+	if err := s.scoped(u.Table()); err != nil {
+		return err
+	}
+	return s.notFound(s.updateCols(u, cols, s.backer))
 }
 
 func (s *dynamicFilter) UpdateAll(us []pouch.Updateable) error {
-	return s.updateAll(us, s.backer)
+	if s.updateAll == nil {
+		return errors.New("no UpdateAll function has been defined")
+	}
+	if err := s.scoped(firstTable(us)); err != nil {
+		return err
+	}
+	return s.notFound(s.updateAll(us, s.backer))
 }
 
 func (s *dynamicFilter) Delete(i pouch.Deleteable) error {
-	return s.dlete(i, s.backer)
+	if s.dlete == nil {
+		return errors.New("no Delete function has been defined")
+	}
+	if err := s.scoped(i.Table()); err != nil {
+		return err
+	}
+	return s.notFound(s.dlete(i, s.backer))
 }
 
 func (s *dynamicFilter) DeleteAll(ds []pouch.Deleteable) error {
-	return s.dleteAll(ds, s.backer)
+	if s.dleteAll == nil {
+		return errors.New("no DeleteAll function has been defined")
+	}
+	if err := s.scoped(firstTable(ds)); err != nil {
+		return err
+	}
+	return s.notFound(s.dleteAll(ds, s.backer))
+}
+
+// HardDelete defers to the defined Delete function, as soft deletion
+// is up to whoever defines it for a dynamic pouch.
+func (s *dynamicFilter) HardDelete(d pouch.Deleteable) error {
+	return s.Delete(d)
+}
+
+// Restore clears the deleted at field of the entity, then has the
+// defined UpdateColumns function update that column.
+func (s *dynamicFilter) Restore(sd pouch.SoftDeleteable) error {
+	col := sd.DeletedAtColumn()
+	if len(col) == 0 {
+		return errors.New("entity does not name a deleted at column")
+	}
+	u, ok := sd.(interface {
+		pouch.Updateable
+		pouch.Gettable
+	})
+	if !ok {
+		return errors.New("dynamic queries can only restore entities that are Updateable and Gettable")
+	}
+
+	fields := u.GetFieldsFor([]string{col})
+	if len(fields) == 0 || fields[0] == nil {
+		return errors.New("entity has no field for column: " + col)
+	}
+	field := reflect.ValueOf(fields[0])
+	if field.Kind() != reflect.Ptr || field.IsNil() {
+		return errors.New("entity has no settable field for column: " + col)
+	}
+	field.Elem().Set(reflect.Zero(field.Elem().Type()))
+	return s.UpdateColumns(u, col)
+}
+
+func (s *dynamicFilter) UpdateWhere(t pouch.Tableable, assignments map[string]interface{}) (int64, error) {
+	return 0, errors.New("dynamic queries do not support updating by constraints")
+}
+
+func (s *dynamicFilter) DeleteWhere(t pouch.Tableable) (int64, error) {
+	return 0, errors.New("dynamic queries do not support deleting by constraints")
+}
+
+func (s *dynamicFilter) AllowUnconstrained() pouch.Query {
+	return s.clone()
+}
+
+// notFound swallows the *pouch.NotFoundError a defined function returns
+// for missing entities, unless the pouch is strict.
+func (s *dynamicFilter) notFound(err error) error {
+	if _, ok := err.(*pouch.NotFoundError); ok && !s.opts.strict {
+		return nil
+	}
+	return err
+}
+
+func (s *dynamicFilter) Clone() pouch.Query {
+	return s.clone()
+}
+
+// clone copies the query, capping its slices so that appending to the
+// copy's never writes to the original's.
+func (s *dynamicFilter) clone() *dynamicFilter {
+	c := *s
+	c.groupBySpecs = c.groupBySpecs[:len(c.groupBySpecs):len(c.groupBySpecs)]
+	c.orderBySpecs = c.orderBySpecs[:len(c.orderBySpecs):len(c.orderBySpecs)]
+	c.constraints = c.constraints[:len(c.constraints):len(c.constraints)]
+	c.preloads = c.preloads[:len(c.preloads):len(c.preloads)]
+	return &c
+}
+
+// related returns a query free of this one's criterions, to find the
+// relations of the entities it found with.
+func (s *dynamicFilter) related() *dynamicFilter {
+	return &dynamicFilter{
+		backer:     s.backer,
+		ctx:        s.ctx,
+		l:          s.l,
+		opts:       s.opts,
+		find:       s.find,
+		findAll:    s.findAll,
+		findEnts:   s.findEnts,
+		create:     s.create,
+		createAll:  s.createAll,
+		update:     s.update,
+		updateCols: s.updateCols,
+		updateAll:  s.updateAll,
+		dlete:      s.dlete,
+		dleteAll:   s.dleteAll,
+	}
 }
 
 func (s *dynamicFilter) GroupBy(spec string) pouch.Query {
-	s.groupBySpecs = append(s.groupBySpecs, spec)
-	return s
+	c := s.clone()
+	c.groupBySpecs = append(c.groupBySpecs, spec)
+	return c
 }
 
 func (s *dynamicFilter) OrderBy(spec string) pouch.Query {
-	s.orderBySpecs = append(s.orderBySpecs, spec)
-	return s
+	c := s.clone()
+	c.orderBySpecs = append(c.orderBySpecs, spec)
+	return c
 }
 
 func (s *dynamicFilter) Where(frag string, vals ...interface{}) pouch.Query {
-	s.constraints = append(s.constraints, constraintPair{
+	c := s.clone()
+	c.constraints = append(c.constraints, constraintPair{
 		frag: frag,
 		vals: vals,
 	})
-	return s
+	return c
 }
 
 func (s *dynamicFilter) Limit(lim int) pouch.Query {
-	s.limit = lim
-	return s
+	c := s.clone()
+	c.limit = lim
+	return c
 }
 
 func (s *dynamicFilter) Offset(off int) pouch.Query {
-	s.offset = off
-	return s
+	c := s.clone()
+	c.offset = off
+	return c
+}
+
+func (s *dynamicFilter) WithDeleted() pouch.Query {
+	c := s.clone()
+	c.deleted = includeDeleted
+	return c
+}
+
+func (s *dynamicFilter) OnlyDeleted() pouch.Query {
+	c := s.clone()
+	c.deleted = onlyDeleted
+	return c
+}
+
+// Preload has FindEntities and FindPage preload the named relations of
+// the entities they find, with the defined FindEntities function, see
+// pouch.Preload.
+func (s *dynamicFilter) Preload(relations ...string) pouch.Query {
+	c := s.clone()
+	c.preloads = append(c.preloads, relations...)
+	return c
+}
+
+func (s *dynamicFilter) After(c pouch.Cursor) pouch.Query {
+	q := s.clone()
+	q.after = c
+	return q
+}
+
+func (s *dynamicFilter) Before(c pouch.Cursor) pouch.Query {
+	q := s.clone()
+	q.before = c
+	return q
+}
+
+func (s *dynamicFilter) Scopes(scopes ...pouch.Scope) pouch.Query {
+	var q pouch.Query = s.clone()
+	for _, scope := range scopes {
+		q = scope(q)
+	}
+	return q
+}
+
+func (s *dynamicFilter) Unscoped() pouch.Query {
+	q := s.clone()
+	q.unscoped = true
+	return q
+}
+
+func (s *dynamicFilter) WithContext(ctx context.Context) pouch.Query {
+	q := s.clone()
+	q.ctx = ctx
+	return q
 }
 
+func (s *dynamicFilter) Context() context.Context {
+	if s.ctx == nil {
+		return context.Background()
+	}
+	return s.ctx
+}
+
+// scoped refuses to run operations on a table with default scopes,
+// unless the query is Unscoped: dynamic queries have no criterions to
+// apply them with, and would otherwise ignore them.
+func (s *dynamicFilter) scoped(table string) error {
+	if s.unscoped || len(s.opts.scopes[table]) == 0 {
+		return nil
+	}
+	return errors.New("dynamic queries cannot apply the default scopes of " + table + ", use Unscoped")
+}
+
+// FindEntities has the defined FindEntities function find every candidate
+// entity, then drops those the query doesn't see (see sees), and orders
+// and paginates the rest in memory as the query asks.
 func (s *dynamicFilter) FindEntities(template pouch.Findable, res *[]pouch.Findable) error {
-	// TODO(ttacon): do it
-	return nil
+	if s.findEnts == nil {
+		return errors.New("no FindEntities function has been defined")
+	}
+	if err := s.scoped(template.Table()); err != nil {
+		return err
+	}
+
+	var found []pouch.Findable
+	if err := s.findEnts(template, &found, s.backer); err != nil {
+		return err
+	}
+	var all = found[:0]
+	for _, f := range found {
+		if s.sees(f) {
+			all = append(all, f)
+		}
+	}
+	order := pouch.KeysetOrder(s.orderBySpecs, template)
+	page, err := pouch.PageEntities(all, order, s.after, s.before, s.offset, s.limit)
+	if err != nil {
+		return err
+	}
+	*res = append(*res, page...)
+	if len(s.preloads) == 0 {
+		return nil
+	}
+	return pouch.Preload(s.related(), page, s.preloads...)
+}
+
+func (s *dynamicFilter) FindPage(template pouch.Findable, res *[]pouch.Findable) (pouch.Cursor, pouch.Cursor, error) {
+	if err := s.scoped(template.Table()); err != nil {
+		return "", "", err
+	}
+	var found = len(*res)
+	if err := s.FindEntities(template, res); err != nil {
+		return "", "", err
+	}
+	order := pouch.KeysetOrder(s.orderBySpecs, template)
+	return pouch.PageCursors((*res)[found:], order, s.after, s.before, s.limit)
 }
 
 ////////// actually making the aioli //////////
@@ -243,9 +619,15 @@ func (d *dynamicPouch) SetFindAll(fn func([]pouch.Findable, interface{}) error)
 func (d *dynamicPouch) SetCreate(fn func(pouch.Createable, interface{}) error)      { d.create = fn }
 func (d *dynamicPouch) SetCreateAll(fn func([]pouch.Createable, interface{}) error) { d.createAll = fn }
 func (d *dynamicPouch) SetUpdate(fn func(pouch.Updateable, interface{}) error)      { d.update = fn }
+func (d *dynamicPouch) SetUpdateColumns(fn func(pouch.Updateable, []string, interface{}) error) {
+	d.updateCols = fn
+}
 func (d *dynamicPouch) SetUpdateAll(fn func([]pouch.Updateable, interface{}) error) { d.updateAll = fn }
 func (d *dynamicPouch) SetDlete(fn func(pouch.Deleteable, interface{}) error)       { d.dlete = fn }
 func (d *dynamicPouch) SetDleteAll(fn func([]pouch.Deleteable, interface{}) error)  { d.dleteAll = fn }
+func (d *dynamicPouch) SetFindEntities(fn func(pouch.Findable, *[]pouch.Findable, interface{}) error) {
+	d.findEnts = fn
+}
 
 func (d *dynamicFilter) SetFind(fn func(pouch.Findable, interface{}) error)      { d.find = fn }
 func (d *dynamicFilter) SetFindAll(fn func([]pouch.Findable, interface{}) error) { d.findAll = fn }
@@ -254,8 +636,14 @@ func (d *dynamicFilter) SetCreateAll(fn func([]pouch.Createable, interface{}) er
 	d.createAll = fn
 }
 func (d *dynamicFilter) SetUpdate(fn func(pouch.Updateable, interface{}) error) { d.update = fn }
+func (d *dynamicFilter) SetUpdateColumns(fn func(pouch.Updateable, []string, interface{}) error) {
+	d.updateCols = fn
+}
 func (d *dynamicFilter) SetUpdateAll(fn func([]pouch.Updateable, interface{}) error) {
 	d.updateAll = fn
 }
 func (d *dynamicFilter) SetDlete(fn func(pouch.Deleteable, interface{}) error)      { d.dlete = fn }
 func (d *dynamicFilter) SetDleteAll(fn func([]pouch.Deleteable, interface{}) error) { d.dleteAll = fn }
+func (d *dynamicFilter) SetFindEntities(fn func(pouch.Findable, *[]pouch.Findable, interface{}) error) {
+	d.findEnts = fn
+}
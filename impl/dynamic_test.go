@@ -1,9 +1,12 @@
 package impl
 
 import (
+	"database/sql"
 	"errors"
 	"fmt"
+	"sync"
 	"testing"
+	"time"
 
 	. "github.com/smartystreets/goconvey/convey"
 	"github.com/ttacon/pouch"
@@ -281,6 +284,268 @@ func TestDynamicPouch(t *testing.T) {
 	})
 }
 
+func TestStrictDynamicPouch(t *testing.T) {
+	Convey("given a dynamic pouch whose delete can't find entities", t, func() {
+		dlete := func(d pouch.Deleteable, i interface{}) error {
+			return &pouch.NotFoundError{Missing: []pouch.Deleteable{d}}
+		}
+		t0 := &dynamoTestWrapper{id: "ghost"}
+
+		Convey("a lenient pouch should treat deleting them as a success", func() {
+			d := NewDynamicPouch(map[string]string{})
+			d.SetDlete(dlete)
+			So(d.Delete(t0), ShouldBeNil)
+			So(d.Where("id = ?", "ghost").Delete(t0), ShouldBeNil)
+		})
+
+		Convey("a strict pouch should report them as not found", func() {
+			d := NewDynamicPouch(map[string]string{}, Strict())
+			d.SetDlete(dlete)
+			err := d.Delete(t0)
+			So(err, ShouldHaveSameTypeAs, &pouch.NotFoundError{})
+			So(err.Error(), ShouldEqual, "entities not found: dynamo:ghost [ghost]")
+			So(d.Where("id = ?", "ghost").Delete(t0), ShouldNotBeNil)
+		})
+	})
+}
+
+func TestDynamicPagination(t *testing.T) {
+	Convey("given a dynamic pouch that can find customers", t, func() {
+		d := NewDynamicPouch([]string{"grace", "ada", "edsger", "barbara", "alan"})
+		d.SetFindEntities(func(template pouch.Findable, res *[]pouch.Findable, i interface{}) error {
+			for id, name := range i.([]string) {
+				*res = append(*res, &Customer{ID: id, Name: name})
+			}
+			return nil
+		})
+		names := func(page []pouch.Findable) []string {
+			var ns []string
+			for _, c := range page {
+				ns = append(ns, c.(*Customer).Name)
+			}
+			return ns
+		}
+
+		Convey("it should order and page through them with cursors", func() {
+			var page []pouch.Findable
+			next, prev, err := d.OrderBy("Name").Limit(2).FindPage(&Customer{}, &page)
+			So(err, ShouldBeNil)
+			So(names(page), ShouldResemble, []string{"ada", "alan"})
+			So(prev, ShouldBeEmpty)
+
+			page = nil
+			next, prev, err = d.OrderBy("Name").Limit(2).After(next).FindPage(&Customer{}, &page)
+			So(err, ShouldBeNil)
+			So(names(page), ShouldResemble, []string{"barbara", "edsger"})
+
+			page = nil
+			next, _, err = d.OrderBy("Name").Limit(2).After(next).FindPage(&Customer{}, &page)
+			So(err, ShouldBeNil)
+			So(names(page), ShouldResemble, []string{"grace"})
+			So(next, ShouldBeEmpty)
+
+			page = nil
+			_, _, err = d.OrderBy("Name").Limit(2).Before(prev).FindPage(&Customer{}, &page)
+			So(err, ShouldBeNil)
+			So(names(page), ShouldResemble, []string{"ada", "alan"})
+		})
+	})
+}
+
+func TestDynamicUpdateColumns(t *testing.T) {
+	Convey("given a dynamic pouch of plants", t, func() {
+		plants := map[int]*Plant{1: {ID: 1, Name: "fern"}}
+		d := NewDynamicPouch(plants)
+		fern := &Plant{ID: 1, Name: "bracken"}
+
+		Convey("with no UpdateColumns function it should error out", func() {
+			d.SetUpdate(func(u pouch.Updateable, i interface{}) error { return nil })
+			err := d.UpdateColumns(fern, "Name")
+			So(err, ShouldNotBeNil)
+			So(err.Error(), ShouldEqual, "no UpdateColumns function has been defined")
+		})
+
+		Convey("it should hand the function the entity and the columns", func() {
+			d.SetUpdateColumns(func(u pouch.Updateable, cols []string, i interface{}) error {
+				plant, ok := u.(*Plant)
+				if !ok {
+					return errors.New("unexpected entity")
+				}
+				stored, ok := i.(map[int]*Plant)[plant.ID]
+				if !ok {
+					return &pouch.NotFoundError{Missing: []pouch.Deleteable{u}}
+				}
+				for j, col := range cols {
+					if col == "Name" {
+						stored.Name = u.FieldsFor(cols)[j].(string)
+					}
+				}
+				return nil
+			})
+			So(d.UpdateColumns(fern, "Name"), ShouldBeNil)
+			So(plants[1].Name, ShouldEqual, "bracken")
+			So(d.UpdateColumns(&Plant{ID: 2}, "Name"), ShouldBeNil)
+		})
+	})
+}
+
+func TestDynamicSoftDeletes(t *testing.T) {
+	Convey("given a dynamic pouch of plants, one of which is soft deleted", t, func() {
+		deletedAt := time.Date(2015, 3, 14, 0, 0, 0, 0, time.UTC)
+		plants := map[int]*Plant{
+			1: {ID: 1, Name: "fern"},
+			2: {ID: 2, Name: "moss", DeletedAt: &deletedAt},
+		}
+		d := NewDynamicPouch(plants)
+		d.SetFind(func(f pouch.Findable, i interface{}) error {
+			plant, ok := i.(map[int]*Plant)[f.(*Plant).ID]
+			if !ok {
+				return sql.ErrNoRows
+			}
+			*f.(*Plant) = *plant
+			return nil
+		})
+		d.SetFindEntities(func(template pouch.Findable, res *[]pouch.Findable, i interface{}) error {
+			for _, plant := range i.(map[int]*Plant) {
+				found := *plant
+				*res = append(*res, &found)
+			}
+			return nil
+		})
+		d.SetUpdateColumns(func(u pouch.Updateable, cols []string, i interface{}) error {
+			plant := i.(map[int]*Plant)[u.(*Plant).ID]
+			for j, col := range cols {
+				if col == "DeletedAt" {
+					plant.DeletedAt = u.FieldsFor(cols)[j].(*time.Time)
+				}
+			}
+			return nil
+		})
+		names := func(q pouch.Queryable) []string {
+			var res []pouch.Findable
+			So(q.OrderBy("Name").FindEntities(&Plant{}, &res), ShouldBeNil)
+			var ns []string
+			for _, p := range res {
+				ns = append(ns, p.(*Plant).Name)
+			}
+			return ns
+		}
+
+		Convey("reads should hide it unless asked for it", func() {
+			So(names(d), ShouldResemble, []string{"fern"})
+			So(names(d.WithDeleted()), ShouldResemble, []string{"fern", "moss"})
+			So(names(d.OnlyDeleted()), ShouldResemble, []string{"moss"})
+
+			So(d.Find(&Plant{ID: 2}), ShouldEqual, sql.ErrNoRows)
+			So(d.WithDeleted().Find(&Plant{ID: 2}), ShouldBeNil)
+			So(d.OnlyDeleted().Find(&Plant{ID: 1}), ShouldEqual, sql.ErrNoRows)
+		})
+
+		Convey("restoring it should clear its deleted at column", func() {
+			moss := &Plant{ID: 2, DeletedAt: &deletedAt}
+			So(d.OnlyDeleted().Restore(moss), ShouldBeNil)
+			So(moss.DeletedAt, ShouldBeNil)
+			So(plants[2].DeletedAt, ShouldBeNil)
+			So(names(d), ShouldResemble, []string{"fern", "moss"})
+		})
+	})
+}
+
+func TestDynamicDefaultScopes(t *testing.T) {
+	Convey("given a dynamic pouch with default scopes for plants", t, func() {
+		plants := map[int]*Plant{1: {ID: 1, Name: "fern"}}
+		d := NewDynamicPouch(plants, DefaultScopes("Plant", func(q pouch.Query) pouch.Query {
+			return q.Where("Name != ?", "")
+		}))
+		d.SetFind(func(f pouch.Findable, i interface{}) error {
+			*f.(*Plant) = *i.(map[int]*Plant)[f.(*Plant).ID]
+			return nil
+		})
+
+		Convey("it should refuse to run queries that would ignore them", func() {
+			err := d.Find(&Plant{ID: 1})
+			So(err, ShouldNotBeNil)
+			So(err.Error(), ShouldEqual, "dynamic queries cannot apply the default scopes of Plant, use Unscoped")
+			So(d.Delete(&Plant{ID: 1}), ShouldNotBeNil)
+		})
+
+		Convey("it should run unscoped queries", func() {
+			fern := &Plant{ID: 1}
+			So(d.Unscoped().Find(fern), ShouldBeNil)
+			So(fern.Name, ShouldEqual, "fern")
+		})
+
+		Convey("it should run queries on other tables", func() {
+			d.SetFindEntities(func(f pouch.Findable, res *[]pouch.Findable, i interface{}) error { return nil })
+			var res []pouch.Findable
+			So(d.Offset(0).FindEntities(&Customer{}, &res), ShouldBeNil)
+		})
+	})
+}
+
+func TestDynamicPreload(t *testing.T) {
+	Convey("given a dynamic pouch of notes and their replies", t, func() {
+		d := NewDynamicPouch(nil)
+		d.SetFindEntities(func(template pouch.Findable, res *[]pouch.Findable, i interface{}) error {
+			switch template.(type) {
+			case *Note:
+				*res = append(*res, &Note{ID: 1}, &Note{ID: 2})
+			case *Reply:
+				*res = append(*res, &Reply{ID: 5, NoteID: 1}, &Reply{ID: 6, NoteID: 1})
+			}
+			return nil
+		})
+
+		Convey("it should attach the preloaded relations of what it finds", func() {
+			var notes []pouch.Findable
+			So(d.Preload("Replies").FindEntities(&Note{}, &notes), ShouldBeNil)
+			So(len(notes), ShouldEqual, 2)
+			So(len(notes[0].(*Note).Replies), ShouldEqual, 2)
+			So(len(notes[1].(*Note).Replies), ShouldEqual, 0)
+		})
+
+		Convey("it should refuse to preload unknown relations", func() {
+			var notes []pouch.Findable
+			So(d.Preload("Authors").FindEntities(&Note{}, &notes), ShouldNotBeNil)
+		})
+	})
+}
+
+func TestDynamicQueriesAreImmutable(t *testing.T) {
+	Convey("given a query shared by goroutines", t, func() {
+		d := NewDynamicPouch([]string{"grace", "ada", "edsger"})
+		d.SetFindEntities(func(template pouch.Findable, res *[]pouch.Findable, i interface{}) error {
+			for id, name := range i.([]string) {
+				*res = append(*res, &Customer{ID: id, Name: name})
+			}
+			return nil
+		})
+		base := d.OrderBy("Name")
+
+		Convey("each branch of it should only see its own criterions", func() {
+			var (
+				wg    sync.WaitGroup
+				sizes = make([]int, 3)
+			)
+			for i := range sizes {
+				wg.Add(1)
+				go func(i int) {
+					defer wg.Done()
+					var res []pouch.Findable
+					base.Clone().Limit(i+1).FindEntities(&Customer{}, &res)
+					sizes[i] = len(res)
+				}(i)
+			}
+			wg.Wait()
+			So(sizes, ShouldResemble, []int{1, 2, 3})
+
+			var res []pouch.Findable
+			So(base.FindEntities(&Customer{}, &res), ShouldBeNil)
+			So(len(res), ShouldEqual, 3)
+		})
+	})
+}
+
 type dynamicTestStruct struct {
 	id    string
 	field string
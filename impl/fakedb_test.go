@@ -0,0 +1,193 @@
+package impl
+
+import (
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"io"
+	"sync"
+)
+
+// fakeDriver is a database/sql driver that records every statement it
+// is asked to run and answers them with canned results, so that the
+// SQL pouch can be tested without a running database.
+type fakeDriver struct{}
+
+var (
+	fakeDBsMu sync.Mutex
+	fakeDBs   = make(map[string]*fakeDB)
+	fakeDBSeq int
+)
+
+func init() {
+	sql.Register("pouchfake", fakeDriver{})
+}
+
+// newFakeDB returns a *sql.DB backed by a new fakeDB.
+func newFakeDB() (*sql.DB, *fakeDB) {
+	fakeDBsMu.Lock()
+	fakeDBSeq++
+	name := fmt.Sprintf("fake%d", fakeDBSeq)
+	f := &fakeDB{affected: 1}
+	fakeDBs[name] = f
+	fakeDBsMu.Unlock()
+
+	db, err := sql.Open("pouchfake", name)
+	if err != nil {
+		panic(err)
+	}
+	return db, f
+}
+
+func (fakeDriver) Open(name string) (driver.Conn, error) {
+	fakeDBsMu.Lock()
+	defer fakeDBsMu.Unlock()
+	f, ok := fakeDBs[name]
+	if !ok {
+		return nil, errors.New("unknown fake db: " + name)
+	}
+	return &fakeConn{db: f}, nil
+}
+
+type fakeStatement struct {
+	query string
+	args  []driver.Value
+}
+
+type fakeDB struct {
+	mu         sync.Mutex
+	statements []fakeStatement
+
+	// canned answers, respond (if set) answers queries instead of
+	// columns and rows
+	columns  []string
+	rows     [][]driver.Value
+	respond  func(query string, args []driver.Value) ([]string, [][]driver.Value)
+	affected int64
+	lastID   int64
+	err      error
+
+	// how many statements were prepared, and closed
+	prepared, closed int
+	// how many transactions were committed, and rolled back
+	committed, rolledBack int
+}
+
+func (f *fakeDB) record(query string, args []driver.Value) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.statements = append(f.statements, fakeStatement{query: query, args: args})
+	return f.err
+}
+
+// last returns the most recently run statement.
+func (f *fakeDB) last() fakeStatement {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if len(f.statements) == 0 {
+		return fakeStatement{}
+	}
+	return f.statements[len(f.statements)-1]
+}
+
+func (f *fakeDB) all() []fakeStatement {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return append([]fakeStatement(nil), f.statements...)
+}
+
+type fakeConn struct {
+	db *fakeDB
+}
+
+func (c *fakeConn) Prepare(query string) (driver.Stmt, error) {
+	c.db.mu.Lock()
+	c.db.prepared++
+	c.db.mu.Unlock()
+	return &fakeStmt{db: c.db, query: query}, nil
+}
+
+func (c *fakeConn) Close() error              { return nil }
+func (c *fakeConn) Begin() (driver.Tx, error) { return fakeTx{db: c.db}, nil }
+
+type fakeTx struct {
+	db *fakeDB
+}
+
+func (tx fakeTx) Commit() error {
+	tx.db.mu.Lock()
+	tx.db.committed++
+	tx.db.mu.Unlock()
+	return nil
+}
+
+func (tx fakeTx) Rollback() error {
+	tx.db.mu.Lock()
+	tx.db.rolledBack++
+	tx.db.mu.Unlock()
+	return nil
+}
+
+type fakeStmt struct {
+	db    *fakeDB
+	query string
+}
+
+func (s *fakeStmt) Close() error {
+	s.db.mu.Lock()
+	s.db.closed++
+	s.db.mu.Unlock()
+	return nil
+}
+
+func (s *fakeStmt) NumInput() int { return -1 }
+
+func (s *fakeStmt) Exec(args []driver.Value) (driver.Result, error) {
+	if err := s.db.record(s.query, args); err != nil {
+		return nil, err
+	}
+	s.db.mu.Lock()
+	defer s.db.mu.Unlock()
+	return fakeResult{lastID: s.db.lastID, affected: s.db.affected}, nil
+}
+
+func (s *fakeStmt) Query(args []driver.Value) (driver.Rows, error) {
+	if err := s.db.record(s.query, args); err != nil {
+		return nil, err
+	}
+	s.db.mu.Lock()
+	defer s.db.mu.Unlock()
+	if s.db.respond != nil {
+		columns, rows := s.db.respond(s.query, args)
+		return &fakeRows{columns: columns, rows: rows}, nil
+	}
+	return &fakeRows{
+		columns: s.db.columns,
+		rows:    append([][]driver.Value(nil), s.db.rows...),
+	}, nil
+}
+
+type fakeResult struct {
+	lastID, affected int64
+}
+
+func (r fakeResult) LastInsertId() (int64, error) { return r.lastID, nil }
+func (r fakeResult) RowsAffected() (int64, error) { return r.affected, nil }
+
+type fakeRows struct {
+	columns []string
+	rows    [][]driver.Value
+}
+
+func (r *fakeRows) Columns() []string { return r.columns }
+func (r *fakeRows) Close() error      { return nil }
+
+func (r *fakeRows) Next(dest []driver.Value) error {
+	if len(r.rows) == 0 {
+		return io.EOF
+	}
+	copy(dest, r.rows[0])
+	r.rows = r.rows[1:]
+	return nil
+}
@@ -0,0 +1,145 @@
+package impl
+
+import (
+	"context"
+	"database/sql/driver"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ttacon/pouch"
+)
+
+func Test_guardedLimits(t *testing.T) {
+	db, fake := newFakeDB()
+	fake.columns = []string{"ID", "Name"}
+	fake.rows = [][]driver.Value{{int64(1), "ada"}}
+	fake.affected = 1
+
+	var started, unblock = make(chan bool), make(chan bool)
+	block := func(st *Statement, next Handler) (*Result, error) {
+		if st.Table == "Customer" && st.Op == "Delete" {
+			started <- true
+			<-unblock
+		}
+		return next(st)
+	}
+	p := pouch.Guarded(SQLPouch(db, Intercept(block)), pouch.GuardPolicy{
+		MaxConcurrent: 1,
+		PerTable:      true,
+		QueueTimeout:  10 * time.Millisecond,
+	})
+
+	var blocked = make(chan error)
+	go func() { blocked <- p.Update(&Note{ID: 1, Body: "hi"}) }()
+	go func() { blocked <- p.Delete(&Customer{ID: 1}) }()
+	<-started
+	// the other operation is the Note's, which has a turn of its own
+	if err := <-blocked; err != nil {
+		t.Fatal("operations on other tables should run, err was: ", err)
+	}
+
+	err := p.Find(&Customer{ID: 1})
+	var overloaded *pouch.OverloadedError
+	if !errors.As(err, &overloaded) || overloaded.Table != "Customer" || pouch.ErrorClass(err) != "overloaded" {
+		t.Error("operations should be shed once they waited too long, err was: ", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if err := p.WithContext(ctx).Find(&Customer{ID: 1}); !errors.As(err, &overloaded) || !errors.Is(err, context.Canceled) {
+		t.Error("operations should be shed once their context is done, err was: ", err)
+	}
+
+	unblock <- true
+	if err := <-blocked; err != nil {
+		t.Fatal("err should have been nil, was: ", err)
+	}
+	if err := p.Find(&Customer{ID: 1}); err != nil {
+		t.Error("operations should run once others are done, err was: ", err)
+	}
+}
+
+func Test_guardedRunInTx(t *testing.T) {
+	db, fake := newFakeDB()
+	fake.columns = []string{"ID", "TenantID", "Body"}
+	fake.rows = [][]driver.Value{{int64(3), int64(7), "hi"}}
+
+	p := pouch.Guarded(pouch.TenantPouch(SQLPouch(db), "TenantID", 7), pouch.GuardPolicy{MaxConcurrent: 1})
+	err := p.(pouch.Transactional).RunInTx(context.Background(), func(tx pouch.Pouch) error {
+		// the transaction holds the only turn, its operations don't wait
+		return tx.Find(&Note{ID: 3})
+	})
+	if err != nil {
+		t.Fatal("err should have been nil, was: ", err)
+	}
+	if stmt := fake.last(); !strings.Contains(stmt.query, "where TenantID = ? AND ID = ?") {
+		t.Error("the transaction's pouch should be decorated as the pouch is, ran: ", stmt.query)
+	}
+}
+
+func Test_guardedBreaker(t *testing.T) {
+	db, fake := newFakeDB()
+	fake.columns = []string{"ID", "Name"}
+	fake.rows = [][]driver.Value{{int64(1), "ada"}}
+	fake.affected = 1
+
+	var (
+		errDown = errors.New("connection refused")
+		down    = true
+		ran     int
+		clock   = time.Now()
+	)
+	outage := func(st *Statement, next Handler) (*Result, error) {
+		ran++
+		if down {
+			return nil, errDown
+		}
+		return next(st)
+	}
+	p := pouch.Guarded(SQLPouch(db, Intercept(outage)), pouch.GuardPolicy{
+		FailureThreshold: 2,
+		OpenTimeout:      time.Minute,
+		Now:              func() time.Time { return clock },
+	})
+
+	for i := 0; i < 2; i++ {
+		if err := p.Find(&Customer{ID: 1}); err != errDown {
+			t.Fatal("err should have been the outage, was: ", err)
+		}
+	}
+	if err := p.Find(&Customer{ID: 1}); err != pouch.ErrCircuitOpen || ran != 2 {
+		t.Errorf("the breaker should be open, ran %d statements and err was: %v", ran, err)
+	}
+
+	clock = clock.Add(59 * time.Second)
+	if err := p.Find(&Customer{ID: 1}); err != pouch.ErrCircuitOpen || ran != 2 {
+		t.Errorf("the breaker should stay open until its timeout, ran %d statements and err was: %v", ran, err)
+	}
+	clock = clock.Add(time.Second)
+	if err := p.Find(&Customer{ID: 1}); err != errDown || ran != 3 {
+		t.Errorf("the breaker should let a probe through, ran %d statements and err was: %v", ran, err)
+	}
+	if err := p.Find(&Customer{ID: 1}); err != pouch.ErrCircuitOpen {
+		t.Error("the breaker should open again when its probe fails, err was: ", err)
+	}
+
+	down = false
+	clock = clock.Add(time.Minute)
+	for i := 0; i < 2; i++ {
+		if err := p.Find(&Customer{ID: 1}); err != nil {
+			t.Fatal("the breaker should close when its probe succeeds, err was: ", err)
+		}
+	}
+
+	// errors that are the caller's don't trip it
+	fake.affected = 0
+	strict := pouch.Guarded(SQLPouch(db, Strict()), pouch.GuardPolicy{FailureThreshold: 1, OpenTimeout: time.Hour})
+	for i := 0; i < 2; i++ {
+		var notFound *pouch.NotFoundError
+		if err := strict.Delete(&Customer{ID: 1}); !errors.As(err, &notFound) {
+			t.Fatal("err should have been a *pouch.NotFoundError, was: ", err)
+		}
+	}
+}
@@ -0,0 +1,202 @@
+package impl
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"reflect"
+
+	"github.com/ttacon/pouch"
+)
+
+// A Statement is a statement a pouch is about to run, and what for.
+type Statement struct {
+	// Op is the operation the statement is run for, i.e. "Find" or
+	// "UpdateWhere", Table the table of the entities it's run on.
+	Op    string
+	Table string
+	Query string
+	Args  []interface{}
+	// Exec is whether the statement is executed (i.e. an insert) rather
+	// than queried for rows.
+	Exec bool
+	// Ctx is the context of the Query the statement is run by.
+	Ctx context.Context
+}
+
+// A Result is what running a Statement returned: its sql.Result if it
+// was executed, or its Rows if it was queried.
+type Result struct {
+	sql.Result
+	Rows *sql.Rows
+}
+
+// A Handler runs a Statement.
+type Handler func(st *Statement) (*Result, error)
+
+// An Interceptor is given every statement a pouch runs, before it runs,
+// and the Handler that runs it. It may change the statement before
+// passing it on to the Handler, look at the Result or error after, or
+// refuse to run the statement by returning an error without calling the
+// Handler, i.e.
+//
+//	func tagged(st *impl.Statement, next impl.Handler) (*impl.Result, error) {
+//		st.Query = "/* " + requestID(st.Ctx) + " */ " + st.Query
+//		return next(st)
+//	}
+//
+// Statements are logged as the pouch made them, and those that are
+// rewritten aren't prepared (see PrepareStatements).
+type Interceptor func(st *Statement, next Handler) (*Result, error)
+
+// Intercept makes the pouch pass every statement it runs through the
+// given interceptors, the first of which is given the statement first.
+func Intercept(interceptors ...Interceptor) Option {
+	return func(o *options) {
+		o.interceptors = append(o.interceptors, interceptors...)
+	}
+}
+
+// errNoResult is returned when an interceptor returns neither a Result
+// nor an error.
+var errNoResult = errors.New("an interceptor returned no result for a statement")
+
+// interceptedExecutor runs the statements of an operation through a
+// chain of interceptors.
+type interceptedExecutor struct {
+	db           pouch.Executor
+	interceptors []Interceptor
+	op, table    string
+	ctx          context.Context
+}
+
+// executor returns the Executor the statements of the given operation
+// on the given table are run with.
+func (s *sqlQuery) executor(op, table string) pouch.Executor {
+	var db = s.db
+	if len(s.opts.interceptors) > 0 {
+		db = &interceptedExecutor{
+			db:           db,
+			interceptors: s.opts.interceptors,
+			op:           op,
+			table:        table,
+			ctx:          s.Context(),
+		}
+	}
+	if s.opts.retry != nil && !inTx(s.db) {
+		db = &retryingExecutor{
+			db:         db,
+			policy:     s.opts.retry,
+			idempotent: op != "Create" && op != "CreateAll",
+			ctx:        s.Context(),
+		}
+	}
+	return db
+}
+
+// inTx reports whether an Executor runs statements in a transaction,
+// which MySQL rolls back whole when one of them deadlocks.
+func inTx(db pouch.Executor) bool {
+	if u, ok := db.(unpreparer); ok {
+		db = u.unprepared()
+	}
+	_, ok := db.(*sql.Tx)
+	return ok
+}
+
+// updater returns the Executor the statements of an operation updating
+// the given entities are run with. Updates of Versioned entities aren't
+// idempotent: retrying one that succeeded but reported an error would
+// conflict with its own update.
+func (s *sqlQuery) updater(op string, us ...pouch.Updateable) pouch.Executor {
+	db := s.executor(op, firstTable(us))
+	if r, ok := db.(*retryingExecutor); ok && anyVersioned(us) {
+		r.idempotent = false
+	}
+	return db
+}
+
+func anyVersioned(us []pouch.Updateable) bool {
+	for _, u := range us {
+		if _, ok := u.(pouch.Versioned); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// firstTable is the table of the first of the given entities, for the
+// statements of the operations on many.
+func firstTable(es interface{}) string {
+	v := reflect.ValueOf(es)
+	if v.Len() == 0 {
+		return ""
+	}
+	return v.Index(0).Interface().(pouch.Tableable).Table()
+}
+
+func (e *interceptedExecutor) run(st *Statement) (*Result, error) {
+	var generated = st.Query
+	var h Handler = func(st *Statement) (*Result, error) {
+		var db = e.db
+		// statements that were rewritten (i.e. tagged with a request ID)
+		// aren't prepared, so they don't crowd out the pouch's own
+		if u, ok := db.(unpreparer); ok && st.Query != generated {
+			db = u.unprepared()
+		}
+		if st.Exec {
+			res, err := db.Exec(st.Query, st.Args...)
+			return &Result{Result: res}, err
+		}
+		rows, err := db.Query(st.Query, st.Args...)
+		return &Result{Rows: rows}, err
+	}
+	for i := len(e.interceptors) - 1; i >= 0; i-- {
+		interceptor, next := e.interceptors[i], h
+		h = func(st *Statement) (*Result, error) {
+			return interceptor(st, next)
+		}
+	}
+
+	res, err := h(st)
+	if err == nil && (res == nil || st.Exec && res.Result == nil || !st.Exec && res.Rows == nil) {
+		return nil, errNoResult
+	}
+	if res == nil {
+		res = &Result{}
+	}
+	return res, err
+}
+
+func (e *interceptedExecutor) statement(query string, args []interface{}, exec bool) *Statement {
+	return &Statement{
+		Op:    e.op,
+		Table: e.table,
+		Query: query,
+		Args:  args,
+		Exec:  exec,
+		Ctx:   e.ctx,
+	}
+}
+
+func (e *interceptedExecutor) Exec(query string, args ...interface{}) (sql.Result, error) {
+	res, err := e.run(e.statement(query, args, true))
+	if err != nil {
+		return nil, err
+	}
+	return res.Result, nil
+}
+
+func (e *interceptedExecutor) Query(query string, args ...interface{}) (*sql.Rows, error) {
+	res, err := e.run(e.statement(query, args, false))
+	if err != nil {
+		return nil, err
+	}
+	return res.Rows, nil
+}
+
+// QueryRow isn't intercepted, as a *sql.Row can't be made for a refused
+// statement, so pouches use Query instead.
+func (e *interceptedExecutor) QueryRow(query string, args ...interface{}) *sql.Row {
+	return e.db.QueryRow(query, args...)
+}
@@ -0,0 +1,75 @@
+package impl
+
+import (
+	"context"
+	"database/sql/driver"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/ttacon/pouch"
+)
+
+type requestKey struct{}
+
+var errFullScan = errors.New("full scans are not allowed")
+
+func Test_intercept(t *testing.T) {
+	db, fake := newFakeDB()
+	fake.columns = []string{"ID", "Name"}
+	fake.rows = [][]driver.Value{{int64(1), "ada"}}
+	fake.affected = 2
+
+	var seen []*Statement
+	observe := func(st *Statement, next Handler) (*Result, error) {
+		res, err := next(st)
+		seen = append(seen, st)
+		return res, err
+	}
+	tag := func(st *Statement, next Handler) (*Result, error) {
+		if id, ok := st.Ctx.Value(requestKey{}).(string); ok {
+			st.Query = "/* " + id + " */ " + st.Query
+		}
+		return next(st)
+	}
+	noFullScans := func(st *Statement, next Handler) (*Result, error) {
+		if !strings.Contains(st.Query, "where") {
+			return nil, errFullScan
+		}
+		return next(st)
+	}
+	p := SQLPouch(db, Intercept(observe, tag, noFullScans))
+
+	ctx := context.WithValue(context.Background(), requestKey{}, "req-1")
+	if err := p.WithContext(ctx).Find(&Customer{ID: 1}); err != nil {
+		t.Fatal("err should have been nil, was: ", err)
+	}
+	if q := fake.last().query; !strings.HasPrefix(q, "/* req-1 */ select") {
+		t.Error("the statement should have been tagged, ran: ", q)
+	}
+	if n, err := p.Where("Name = ?", "ada").UpdateWhere(&Customer{}, map[string]interface{}{"Name": "grace"}); n != 2 || err != nil {
+		t.Errorf("expected 2 updated rows, had: %d (%v)", n, err)
+	}
+
+	var ran = len(fake.all())
+	var customers []pouch.Findable
+	if err := p.Offset(0).FindEntities(&Customer{}, &customers); err != errFullScan {
+		t.Error("full scans should be refused, was: ", err)
+	}
+	if len(fake.all()) != ran {
+		t.Error("refused statements should not reach the database, ran: ", fake.last().query)
+	}
+
+	if len(seen) != 3 {
+		t.Fatal("expected every statement to be seen, saw: ", len(seen))
+	}
+	for i, expected := range []Statement{
+		{Op: "Find", Table: "Customer"},
+		{Op: "UpdateWhere", Table: "Customer", Exec: true},
+		{Op: "FindEntities", Table: "Customer"},
+	} {
+		if st := seen[i]; st.Op != expected.Op || st.Table != expected.Table || st.Exec != expected.Exec {
+			t.Errorf("expected statement %d to be for %s %s, was for: %s %s", i, expected.Op, expected.Table, st.Op, st.Table)
+		}
+	}
+}
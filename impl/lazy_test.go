@@ -0,0 +1,31 @@
+package impl
+
+import (
+	"testing"
+
+	"github.com/ttacon/pouch"
+)
+
+func Test_lazyLoad(t *testing.T) {
+	var (
+		l     pouch.Lazy
+		finds int
+	)
+	find := func() (pouch.Findable, error) {
+		finds++
+		return &Customer{ID: finds}, nil
+	}
+
+	var id = 3
+	if _, err := l.Load("Customer", &id, find); err != nil {
+		t.Fatal("err should have been nil, was: ", err)
+	}
+	var same = 3
+	if _, err := l.Load("Customer", &same, find); err != nil || finds != 1 {
+		t.Error("a key pointing to the same value should load the memoized entity, found: ", finds, err)
+	}
+	id = 4
+	if _, err := l.Load("Customer", &id, find); err != nil || finds != 2 {
+		t.Error("a key pointing to another value should load again, found: ", finds, err)
+	}
+}
@@ -1,69 +1,233 @@
 package impl
 
 import (
+	"fmt"
 	"log"
 	"os"
+	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/ttacon/chalk"
+	"github.com/ttacon/pouch"
 )
 
 type Logger interface {
 	Print(v ...interface{})
 	V(level VerbosityLevel) Logger
 	SetVerbosity(level VerbosityLevel)
+	// Log logs a statement a pouch ran, at DEBUG level (WARN if it was
+	// slow, see SlowQueryThreshold), and with the values bound to it only
+	// at TRACE level.
+	Log(e *Entry)
 }
 
+// A Loggable pouch can have its Logger, or the verbosity of it, changed
+// while it is in use, which affects the queries built from it too. The
+// pouches of this package are all Loggable, i.e.
+//
+//	SQLPouch(db).(Loggable).SetVerbosity(TRACE)
+type Loggable interface {
+	SetLogger(l Logger)
+	SetVerbosity(level VerbosityLevel)
+}
+
+// An Entry describes a statement a pouch ran, and how it went.
+type Entry struct {
+	// Op is the kind of statement, i.e. "select" or "soft delete".
+	Op       string
+	Table    string
+	Query    string
+	Args     []interface{}
+	Duration time.Duration
+	// Rows is how many rows the statement returned or affected.
+	Rows int64
+	Err  error
+
+	// Slow is whether the statement took longer than the pouch's
+	// SlowQueryThreshold, only slow statements have a Fingerprint, and
+	// those handed to OnSlowQuery a Plan if they were explained (see
+	// ExplainSlowQueries).
+	Slow        bool
+	Fingerprint string
+	Plan        string
+	PlanErr     error
+}
+
+// String formats the entry the way the default Logger prints it at
+// TRACE level.
+func (e *Entry) String() string {
+	return e.format(true)
+}
+
+func (e *Entry) format(values bool) string {
+	var s = fmt.Sprintf("[%s] %s (%d rows in %s):\n%s",
+		e.Op, e.Table, e.Rows, e.Duration, e.Query)
+	if values {
+		s += fmt.Sprintf(", with values: %v", e.Args)
+	}
+	if e.Err != nil {
+		s += "\nerror: " + e.Err.Error()
+	}
+	if e.Slow {
+		s += "\nslow, fingerprint: " + e.Fingerprint
+	}
+	if e.Plan != "" {
+		s += "\nplan:\n" + e.Plan
+	}
+	if e.PlanErr != nil {
+		s += "\nexplaining failed: " + e.PlanErr.Error()
+	}
+	return s
+}
+
+// A VerbosityLevel is the least level a Logger logs at, the levels
+// are ordered from the most to the least verbose.
 type VerbosityLevel int
 
 const (
+	// TRACE logs statements with the values bound to them.
 	TRACE VerbosityLevel = iota
+	// DEBUG logs statements, without their values.
+	DEBUG
 	INFO
 	WARN
 	ERROR
+	// SILENT logs nothing.
+	SILENT
 )
 
 type logger struct {
-	l             *log.Logger
-	currVerbosity VerbosityLevel
+	l *log.Logger
+	// the VerbosityLevel, it may be set while the logger is in use
+	currVerbosity int32
 }
 
-var defaultVerbosity = INFO
+// defaultVerbosity is SILENT so that statements, and the values bound to
+// them, aren't logged in production unless asked for.
+var defaultVerbosity = SILENT
 
 func SetDefaultVerbosity(level VerbosityLevel) {
 	defaultVerbosity = level
 }
 
-func defaultLogger() Logger {
+// NewLogger returns a Logger like the default one, which prints to
+// the given standard library logger.
+func NewLogger(l *log.Logger) Logger {
 	return &logger{
-		l:             log.New(os.Stdout, "", 0),
-		currVerbosity: defaultVerbosity,
+		l:             l,
+		currVerbosity: int32(defaultVerbosity),
 	}
 }
 
+func defaultLogger() Logger {
+	return NewLogger(log.New(os.Stdout, "", 0))
+}
+
 func (l *logger) Print(v ...interface{}) {
 	currentTime := chalk.Yellow.Color(time.Now().Format("2006-02-01 15:04:05"))
 	v = append([]interface{}{currentTime}, v...)
 	l.l.Println(v...)
 }
 
+func (l *logger) Log(e *Entry) {
+	switch v := l.verbosity(); {
+	case v <= TRACE:
+		l.Print(e.format(true))
+	case v <= DEBUG, e.Slow && v <= WARN:
+		l.Print(e.format(false))
+	}
+}
+
+// V returns a Logger which only prints if the given level is at least
+// as high as the logger's verbosity.
 func (l *logger) V(level VerbosityLevel) Logger {
-	return verbosityLogger(l.currVerbosity == level)
+	return verbosityLogger{l: l, on: level >= l.verbosity()}
+}
+
+func (l *logger) SetVerbosity(level VerbosityLevel) {
+	atomic.StoreInt32(&l.currVerbosity, int32(level))
 }
 
-func (l *logger) SetVerbosity(level VerbosityLevel) { l.currVerbosity = level }
+func (l *logger) verbosity() VerbosityLevel {
+	return VerbosityLevel(atomic.LoadInt32(&l.currVerbosity))
+}
 
-type verbosityLogger bool
+// verbosityLogger prints through the Logger it came from, if it's on.
+type verbosityLogger struct {
+	l  Logger
+	on bool
+}
 
 func (vl verbosityLogger) Print(v ...interface{}) {
-	if vl {
-		currentTime := chalk.Yellow.Color(time.Now().Format("2006-02-01 15:04:05"))
-		v = append([]interface{}{currentTime}, v...)
-		// TODO(ttacon): don't be a baby and not use the Logger
-		// this is from, this will need to change when we allow custom Loggers
-		log.Println(v...)
+	if vl.on {
+		vl.l.Print(v...)
+	}
+}
+
+func (vl verbosityLogger) Log(e *Entry) {
+	if vl.on {
+		vl.l.Log(e)
 	}
 }
 
-func (v verbosityLogger) V(level VerbosityLevel) Logger     { return v }
-func (v verbosityLogger) SetVerbosity(level VerbosityLevel) {}
+func (vl verbosityLogger) V(level VerbosityLevel) Logger     { return vl }
+func (vl verbosityLogger) SetVerbosity(level VerbosityLevel) {}
+
+// pouchLogger is the Logger of a pouch and the queries built from it,
+// it spots slow statements (see SlowQueryThreshold) and redacts the
+// values of sensitive columns (see Redact) from the statements they
+// log, and the pouch's Logger is switched through it while they're in
+// use.
+type pouchLogger struct {
+	mu sync.RWMutex
+	l  Logger
+	// the sensitive columns, lower cased, by table
+	redacted map[string]map[string]bool
+	slow     *slowQueries
+}
+
+func newPouchLogger(o options) *pouchLogger {
+	return &pouchLogger{l: o.logr(), redacted: o.redacted, slow: o.slow}
+}
+
+func (s *pouchLogger) logger() Logger {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.l
+}
+
+func (s *pouchLogger) set(l Logger) {
+	s.mu.Lock()
+	s.l = l
+	s.mu.Unlock()
+}
+
+func (s *pouchLogger) Print(v ...interface{}) { s.logger().Print(v...) }
+
+func (s *pouchLogger) Log(e *Entry) {
+	if s.slow != nil {
+		e = s.slow.check(e)
+	}
+	// explaining needs the values bound to the statement
+	unredacted := e
+	e = s.redact(e)
+	s.logger().Log(e)
+	if e.Slow {
+		s.slow.report(e, unredacted)
+	}
+}
+func (s *pouchLogger) V(level VerbosityLevel) Logger     { return s.logger().V(level) }
+func (s *pouchLogger) SetVerbosity(level VerbosityLevel) { s.logger().SetVerbosity(level) }
+
+// spanLogger adds the statements it logs to a span, without the values
+// bound to them.
+type spanLogger struct {
+	Logger
+	span pouch.Span
+}
+
+func (s spanLogger) Log(e *Entry) {
+	s.span.SetAttribute(pouch.AttrStatement, e.Query)
+	s.Logger.Log(e)
+}
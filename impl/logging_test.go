@@ -0,0 +1,235 @@
+package impl
+
+import (
+	"bytes"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"log"
+	"log/slog"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ttacon/pouch"
+)
+
+// entryLogger keeps the entries it's given.
+type entryLogger struct {
+	verbosityLogger
+	entries []*Entry
+}
+
+func (e *entryLogger) Log(entry *Entry) { e.entries = append(e.entries, entry) }
+
+func Test_withLogger(t *testing.T) {
+	db, fake := newFakeDB()
+	fake.columns = []string{"ID", "Name"}
+	fake.rows = [][]driver.Value{{int64(1), "ada"}, {int64(2), "grace"}}
+
+	var logr = &entryLogger{}
+	p := SQLPouch(db, WithLogger(logr))
+	if err := p.Find(&Customer{ID: 1}); err != nil {
+		t.Fatal("err should have been nil, was: ", err)
+	}
+	fake.err = errors.New("gone away")
+	p.Delete(&Customer{ID: 1})
+
+	if len(logr.entries) != 2 {
+		t.Fatal("expected an entry per statement, had: ", len(logr.entries))
+	}
+	found, failed := logr.entries[0], logr.entries[1]
+	if found.Op != "select" || found.Table != "Customer" || found.Rows != 1 || found.Err != nil {
+		t.Error("unexpected entry for a find: ", found)
+	}
+	if failed.Op != "delete" || failed.Err == nil || len(failed.Args) != 1 {
+		t.Error("unexpected entry for a failed delete: ", failed)
+	}
+}
+
+func Test_slogLogger(t *testing.T) {
+	db, fake := newFakeDB()
+	fake.affected = 3
+
+	var buf bytes.Buffer
+	h := slog.NewTextHandler(&buf, &slog.HandlerOptions{Level: slog.LevelDebug})
+	p := SQLPouch(db, WithLogger(SlogLogger(slog.New(h))))
+	if _, err := p.Where("Name = ?", "kale").DeleteWhere(&Food{}); err != nil {
+		t.Fatal("err should have been nil, was: ", err)
+	}
+
+	out := buf.String()
+	for _, attr := range []string{"level=DEBUG", `msg="pouch delete where"`, "op=", "table=Food", "rows=3", "duration="} {
+		if !strings.Contains(out, attr) {
+			t.Errorf("expected %s to be logged, logged: %s", attr, out)
+		}
+	}
+}
+
+func Test_verbosity(t *testing.T) {
+	var buf bytes.Buffer
+	l := NewLogger(log.New(&buf, "", 0))
+	l.V(ERROR).Print("failed")
+	if buf.Len() != 0 {
+		t.Error("the default logger should be silent, printed: ", buf.String())
+	}
+
+	l.SetVerbosity(INFO)
+	l.V(WARN).Print("warned")
+	l.V(TRACE).Print("traced")
+	if out := buf.String(); !strings.Contains(out, "warned") || strings.Contains(out, "traced") {
+		t.Error("levels at or above the verbosity should print, printed: ", out)
+	}
+
+	db, fake := newFakeDB()
+	fake.columns = []string{"ID", "Name"}
+	fake.rows = [][]driver.Value{{int64(1), "ada"}}
+	buf.Reset()
+	p := SQLPouch(db, WithLogger(l))
+	p.Find(&Customer{ID: 1})
+	if buf.Len() != 0 {
+		t.Error("statements should not be logged at INFO, logged: ", buf.String())
+	}
+
+	p.(Loggable).SetVerbosity(DEBUG)
+	q := p.Where("Name = ?", "ada")
+	p.Find(&Customer{ID: 1})
+	if out := buf.String(); !strings.Contains(out, "[select] Customer") || strings.Contains(out, "with values") {
+		t.Error("statements should be logged without their values at DEBUG, logged: ", out)
+	}
+
+	var logr = &entryLogger{}
+	p.(Loggable).SetLogger(logr)
+	var res []pouch.Findable
+	q.FindEntities(&Customer{}, &res)
+	if len(logr.entries) != 1 {
+		t.Error("queries should log through the logger set on their pouch, had: ", len(logr.entries))
+	}
+}
+
+func Test_traceLogsValues(t *testing.T) {
+	var buf bytes.Buffer
+	l := NewLogger(log.New(&buf, "", 0))
+	l.SetVerbosity(TRACE)
+	l.Log(&Entry{Op: "select", Table: "Customer", Query: "SELECT ...", Args: []interface{}{"secret"}})
+	if !strings.Contains(buf.String(), "with values: [secret]") {
+		t.Error("statements should be logged with their values at TRACE, logged: ", buf.String())
+	}
+}
+
+func Test_redact(t *testing.T) {
+	db, fake := newFakeDB()
+	fake.columns = []string{"ID", "TenantID", "Body"}
+
+	var logr = &entryLogger{}
+	p := SQLPouch(db, WithLogger(logr), Redact("Note", "body"))
+	var notes []pouch.Findable
+	p.Create(&Note{TenantID: 7, Body: "hunter2"})
+	p.Where("TenantID = ?", 7).Where("Note.Body in (?, ?)", "a", "b").FindEntities(&Note{}, &notes)
+	p.Where("lower(Body) = ?", "hunter2").FindEntities(&Note{}, &notes)
+	p.Find(&Customer{ID: 1})
+	// joined from another table than the template's
+	p.(pouch.RawQueryable).RawQuery(&Customer{}, "select c.ID from Customer c join Note n on n.TenantID = c.ID where n.Body = ?", "hunter2")
+
+	var expected = [][]interface{}{
+		{int64(7), redactedValue},
+		{7, redactedValue, redactedValue},
+		{redactedValue},
+		{1},
+		{redactedValue},
+	}
+	if len(logr.entries) != len(expected) {
+		t.Fatal("expected an entry per statement, had: ", len(logr.entries))
+	}
+	for i, e := range logr.entries {
+		if fmt.Sprint(e.Args) != fmt.Sprint(expected[i]) {
+			t.Errorf("expected %s to be logged with %v, was: %v", e.Query, expected[i], e.Args)
+		}
+	}
+}
+
+func Test_boundColumns(t *testing.T) {
+	var tests = []struct {
+		query    string
+		expected []string
+	}{
+		{"insert into User(\n  Name, `Email`\n) values (\n  ?, ?\n)", []string{"Name", "Email"}},
+		{"update User\nset Name = ?, Email = ?\nwhere ID = ?", []string{"Name", "Email", "ID"}},
+		{"select * from User where u.Email like ? and Age between ? and ?", []string{"Email", "Age", "Age"}},
+		{"select * from User where Name = 'a = ?' and ID not in (?, ?)", []string{"ID", "ID"}},
+		{"select * from User where ? = Email or (ID, Name) > (?, ?)", []string{"", "", ""}},
+	}
+	for _, test := range tests {
+		if cols := boundColumns(test.query); fmt.Sprint(cols) != fmt.Sprint(test.expected) {
+			t.Errorf("expected the columns of %q to be %q, were: %q", test.query, test.expected, cols)
+		}
+	}
+}
+
+func Test_slowQueries(t *testing.T) {
+	db, fake := newFakeDB()
+	fake.columns = []string{"ID", "Name"}
+	fake.rows = [][]driver.Value{{int64(1), "ada"}}
+	explainDB, explainer := newFakeDB()
+	explainer.columns = []string{"id", "table", "key"}
+	explainer.rows = [][]driver.Value{{int64(1), "Customer", nil}}
+
+	var (
+		logr = &entryLogger{}
+		slow = make(chan *Entry, 2)
+	)
+	p := SQLPouch(db, WithLogger(logr),
+		SlowQueryThreshold(time.Nanosecond),
+		ExplainSlowQueries(explainDB, time.Hour),
+		OnSlowQuery(func(e *Entry) { slow <- e }))
+	p.Find(&Customer{ID: 1})
+	p.Find(&Customer{ID: 2})
+
+	if len(logr.entries) != 2 || logr.entries[0].Plan != "" {
+		t.Fatal("expected both statements to be logged without waiting for a plan, had: ", logr.entries)
+	}
+	// the explained statement is handed over once it's explained
+	var first, second *Entry
+	for i := 0; i < 2; i++ {
+		select {
+		case e := <-slow:
+			if fmt.Sprint(e.Args[0]) == "1" {
+				first = e
+			} else {
+				second = e
+			}
+		case <-time.After(time.Second):
+			t.Fatal("expected both statements to be handed over as slow")
+		}
+	}
+	if first == nil || second == nil {
+		t.Fatal("expected each statement to be handed over once")
+	}
+	if !first.Slow || first.Fingerprint != "select id, name from customer where id = ?" {
+		t.Error("unexpected fingerprint: ", first.Fingerprint)
+	}
+	if first.Plan != "id\ttable\tkey\n1\tCustomer\tNULL" || first.PlanErr != nil {
+		t.Errorf("unexpected plan: %q (%v)", first.Plan, first.PlanErr)
+	}
+	if q := explainer.last(); q.query != "explain "+first.Query || len(q.args) != 1 {
+		t.Error("the slow statement should have been explained with its values, explained: ", q.query)
+	}
+	if second.Plan != "" || len(explainer.all()) != 1 {
+		t.Error("explaining should be rate limited, explained: ", len(explainer.all()))
+	}
+}
+
+func Test_fingerprint(t *testing.T) {
+	var tests = []struct {
+		query, expected string
+	}{
+		{"SELECT ID\nFROM User WHERE Name = 'ada' AND Age IN (1, 2,3)", "select id from user where name = ? and age in (?+)"},
+		{"insert into User(\n  Name, Email\n) values (\n  ?, ?\n)", "insert into user (name, email) values (?+)"},
+		{"update User set Name = \"it's\" where ID = 4.5", "update user set name = ? where id = ?"},
+	}
+	for _, test := range tests {
+		if f := Fingerprint(test.query); f != test.expected {
+			t.Errorf("expected the fingerprint of %q to be %q, was: %q", test.query, test.expected, f)
+		}
+	}
+}
@@ -0,0 +1,53 @@
+package impl
+
+import (
+	"database/sql/driver"
+	"testing"
+
+	"github.com/ttacon/pouch"
+)
+
+func Test_instrumented(t *testing.T) {
+	db, fake := newFakeDB()
+	fake.columns = []string{"ID", "Name"}
+	fake.rows = [][]driver.Value{{int64(1), "ada"}}
+	fake.affected = 3
+
+	var metrics pouch.MemoryRegistry
+	p := pouch.Instrumented(SQLPouch(db), &metrics)
+	if err := p.Find(&Customer{ID: 1}); err != nil {
+		t.Fatal("err should have been nil, was: ", err)
+	}
+	fake.rows = nil
+	if err := p.Find(&Customer{ID: 2}); err == nil {
+		t.Fatal("finding a missing customer should fail")
+	}
+	if _, err := p.Where("Name = ?", "kale").DeleteWhere(&Food{}); err != nil {
+		t.Fatal("err should have been nil, was: ", err)
+	}
+	fake.affected = 0
+	if err := p.Delete(&Customer{ID: 2}); err != nil {
+		t.Fatal("err should have been nil, was: ", err)
+	}
+
+	for _, c := range []struct {
+		name     string
+		labels   []string
+		expected float64
+	}{
+		{pouch.MetricOperations, []string{"Find", "Customer"}, 2},
+		{pouch.MetricErrors, []string{"Find", "Customer", "not_found"}, 1},
+		{pouch.MetricRows, []string{"Find", "Customer"}, 1},
+		{pouch.MetricRows, []string{"DeleteWhere", "Food"}, 3},
+		{pouch.MetricOperations, []string{"Delete", "Customer"}, 1},
+		{pouch.MetricRows, []string{"Delete", "Customer"}, 0},
+	} {
+		if v := metrics.Value(c.name, c.labels...); v != c.expected {
+			t.Errorf("expected %s%v to be %v, was: %v", c.name, c.labels, c.expected, v)
+		}
+	}
+	if n := metrics.Count(pouch.MetricDuration, "Find", "Customer"); n != 2 {
+		t.Error("expected the latency of both finds to be observed, had: ", n)
+	}
+
+}
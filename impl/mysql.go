@@ -1,149 +1,302 @@
 package impl
 
 import (
+	"context"
+	"database/sql"
 	"errors"
 	"fmt"
+	"sort"
 	"strings"
+	"time"
 
 	"github.com/ttacon/builder"
 	"github.com/ttacon/pouch"
 )
 
+// now is used to timestamp soft deletions, it is a variable so
+// that tests can control it.
+var now = time.Now
+
 ////////// SQL Pouch implementation //////////
 type sqlPouch struct {
-	db pouch.Executor
-	l  Logger
+	db   pouch.Executor
+	l    *pouchLogger
+	opts options
 }
 
-func SQLPouch(db pouch.Executor) pouch.Pouch {
+func SQLPouch(db pouch.Executor, opts ...Option) pouch.Pouch {
+	o := newOptions(opts)
+	if p, ok := db.(preparer); ok && o.prepare > 0 {
+		db = newStmtCache(p, o.prepare)
+	}
 	return &sqlPouch{
-		db: db,
-		l:  defaultLogger(),
+		db:   db,
+		l:    newPouchLogger(o),
+		opts: o,
 	}
 }
 
-func (s *sqlPouch) GroupBy(spec string) pouch.Query {
+// SetLogger makes the pouch, and the queries built from it, log through
+// the given Logger from now on.
+func (s *sqlPouch) SetLogger(l Logger) {
+	s.l.set(l)
+}
+
+// SetVerbosity sets the verbosity of the pouch's Logger.
+func (s *sqlPouch) SetVerbosity(level VerbosityLevel) {
+	s.l.SetVerbosity(level)
+}
+
+// query returns a blank query which every operation on the pouch
+// goes through, so that the pouch and its queries share the same
+// semantics (i.e. hiding soft deleted entities).
+func (s *sqlPouch) query() *sqlQuery {
 	return &sqlQuery{
-		db:           s.db,
-		groupBySpecs: []string{spec},
-		l:            s.l,
+		db:   s.db,
+		l:    s.l,
+		opts: s.opts,
 	}
 }
 
+func (s *sqlPouch) GroupBy(spec string) pouch.Query {
+	return s.query().GroupBy(spec)
+}
+
 func (s *sqlPouch) OrderBy(spec string) pouch.Query {
-	return &sqlQuery{
-		db:           s.db,
-		orderBySpecs: []string{spec},
-		l:            s.l,
-	}
+	return s.query().OrderBy(spec)
 }
 
 func (s *sqlPouch) Where(frag string, vals ...interface{}) pouch.Query {
-	return &sqlQuery{
-		db: s.db,
-		constraints: []constraintPair{
-			constraintPair{
-				frag: frag,
-				vals: vals,
-			},
-		},
-		l: s.l,
-	}
+	return s.query().Where(frag, vals...)
 }
 
 func (s *sqlPouch) Limit(lim int) pouch.Query {
-	return &sqlQuery{
-		db:    s.db,
-		limit: lim,
-		l:     s.l,
-	}
+	return s.query().Limit(lim)
 }
 
 func (s *sqlPouch) Offset(off int) pouch.Query {
-	return &sqlQuery{
-		db:     s.db,
-		offset: off,
-		l:      s.l,
-	}
+	return s.query().Offset(off)
+}
+
+func (s *sqlPouch) WithDeleted() pouch.Query {
+	return s.query().WithDeleted()
+}
+
+func (s *sqlPouch) OnlyDeleted() pouch.Query {
+	return s.query().OnlyDeleted()
+}
+
+func (s *sqlPouch) Preload(relations ...string) pouch.Query {
+	return s.query().Preload(relations...)
+}
+
+func (s *sqlPouch) After(c pouch.Cursor) pouch.Query {
+	return s.query().After(c)
+}
+
+func (s *sqlPouch) Before(c pouch.Cursor) pouch.Query {
+	return s.query().Before(c)
+}
+
+func (s *sqlPouch) Scopes(scopes ...pouch.Scope) pouch.Query {
+	return s.query().Scopes(scopes...)
+}
+
+func (s *sqlPouch) Unscoped() pouch.Query {
+	return s.query().Unscoped()
+}
+
+func (s *sqlPouch) WithContext(ctx context.Context) pouch.Query {
+	return s.query().WithContext(ctx)
 }
 
 func (s *sqlPouch) Find(i pouch.Findable) error {
-	return findEntity(s.db, i, "", nil, s.l)
+	return s.query().Find(i)
 }
 
 func (s *sqlPouch) FindAll(fs []pouch.Findable) error {
-	// TODO(ttaco): do it
-	return nil
+	return s.query().FindAll(fs)
 }
 
 func (s *sqlPouch) Create(i pouch.Createable) error {
-	return createEntity(s.db, i, "", s.l)
+	return s.query().Create(i)
 }
 
 func (s *sqlPouch) CreateAll(cs []pouch.Createable) error {
-	return createAll(s.db, cs, s.l)
+	return s.query().CreateAll(cs)
 }
 
 func (s *sqlPouch) Update(u pouch.Updateable) error {
-	return updateEntity(s.db, u, "", s.l)
+	return s.query().Update(u)
 }
 
-func (s *sqlPouch) UpdateAll(u []pouch.Updateable) error {
-	// TODO(ttaco): do it
-	return nil
+func (s *sqlPouch) UpdateColumns(u pouch.Updateable, cols ...string) error {
+	return s.query().UpdateColumns(u, cols...)
+}
+
+func (s *sqlPouch) UpdateAll(us []pouch.Updateable) error {
+	return s.query().UpdateAll(us)
 }
 
 func (s *sqlPouch) Delete(i pouch.Deleteable) error {
-	return deleteEntity(s.db, i, "", s.l)
+	return s.query().Delete(i)
 }
 
 func (s *sqlPouch) DeleteAll(ds []pouch.Deleteable) error {
-	return deleteAll(s.db, ds, s.l)
+	return s.query().DeleteAll(ds)
 }
 
-// TODO(ttacon): reuse these as we add other dialects
-func findEntity(db pouch.Executor, i pouch.Findable, rest string, ps []interface{}, logr Logger) error {
-	cols, fields := i.GetAllFields()
-	if len(cols) == 0 || len(fields) == 0 {
-		return errors.New("must provide columns to select from")
+// RawQuery runs the given hand-written query, filling a copy of the
+// template for every row it returns by matching the row's columns to the
+// template's. Columns the template doesn't know are an error, unless the
+// pouch was created with IgnoreUnknownColumns.
+func (s *sqlPouch) RawQuery(template pouch.Findable, query string, args ...interface{}) (res []pouch.Findable, err error) {
+	start := time.Now()
+	defer func() {
+		logStatement(s.l, "raw", template.Table(), query, args, start, int64(len(res)), err)
+	}()
+
+	rows, err := s.query().executor("RawQuery", template.Table()).Query(query, args...)
+	if err != nil {
+		return nil, err
 	}
+	defer rows.Close()
 
-	table := i.Table()
-	if len(table) == 0 {
-		return errors.New("entity is not known to map to any table")
+	cols, err := rows.Columns()
+	if err != nil {
+		return nil, err
 	}
-
-	var query = builder.NewBuilderString("select ")
+	known := template.GetFieldsFor(cols)
 	for i, col := range cols {
-		if i > 0 {
-			query.WriteString(",\n  ")
+		if (i >= len(known) || known[i] == nil) && !s.opts.ignoreUnknown {
+			return nil, errors.New("raw query returned a column the entity has no field for: " + col)
 		}
-		query.WriteString(col)
 	}
-	query.WriteString("\nfrom " + table + "\n")
 
-	ids, vals := i.IdentifiableFields()
-	if len(ids) == 0 || len(vals) == 0 {
-		return errors.New("no identifying information for entity")
+	for rows.Next() {
+		cop := template.FindableCopy()
+		fields := cop.GetFieldsFor(cols)
+		var dest = make([]interface{}, len(cols))
+		for i := range dest {
+			if i < len(fields) && fields[i] != nil {
+				dest[i] = fields[i]
+			} else {
+				dest[i] = new(interface{})
+			}
+		}
+		if err = rows.Scan(dest...); err != nil {
+			return nil, err
+		}
+		snapshot(cop, nil)
+		res = append(res, cop)
 	}
+	return res, rows.Err()
+}
+
+// logStatement logs a statement that was run, and how it went.
+func logStatement(logr Logger, op, table, query string, args []interface{}, start time.Time, rows int64, err error) {
+	logr.Log(&Entry{
+		Op:       op,
+		Table:    table,
+		Query:    query,
+		Args:     args,
+		Duration: time.Since(start),
+		Rows:     rows,
+		Err:      err,
+	})
+}
 
-	query.WriteString("where ")
+// logExec logs a statement that was executed, going by its result.
+func logExec(logr Logger, op, table, query string, args []interface{}, start time.Time, res sql.Result, err error) {
+	var rows int64
+	if err == nil {
+		rows, _ = res.RowsAffected()
+	}
+	logStatement(logr, op, table, query, args, start, rows, err)
+}
 
-	if len(rest) == 0 {
-		ps = append(vals, ps...)
-		for i, id := range ids {
-			if i > 0 && i < len(ids)-1 {
-				query.WriteString(" AND ")
+// rowCount is how many rows a single row query returned.
+func rowCount(err error) int64 {
+	if err != nil {
+		return 0
+	}
+	return 1
+}
+
+// selectStatement is the start of a select of the given columns of a
+// table, its clauses follow.
+func selectStatement(table string, cols []string) string {
+	return memoize(func() string {
+		var query = builder.NewBuilderString("select ")
+		for i, col := range cols {
+			if i > 0 {
+				query.WriteString(",\n  ")
 			}
-			query.WriteString(id + " = ? ")
+			query.WriteString(col)
 		}
-	} else {
-		query.WriteString(rest)
+		query.WriteString("\nfrom " + table + "\n")
+		return query.String()
+	}, append([]string{"select", table}, cols...)...)
+}
+
+// insertStatement is an insert of the given columns into a table.
+func insertStatement(table string, cols []string) string {
+	return memoize(func() string {
+		placeholders := "?"
+		if len(cols) > 1 {
+			placeholders += strings.Repeat(", ?", len(cols)-1)
+		}
+		var query = builder.NewBuilderString("insert into " + table)
+		query.WriteString("(\n  " + strings.Join(cols, ", ") + "\n) values ")
+		query.WriteString("(\n  " + placeholders + "\n)")
+		return query.String()
+	}, append([]string{"insert", table}, cols...)...)
+}
+
+// queryRow scans the first row the given query returns into the given
+// fields, like Executor.QueryRow, so that interceptors can refuse it.
+func queryRow(db pouch.Executor, query string, args []interface{}, fields []interface{}) error {
+	rows, err := db.Query(query, args...)
+	if err != nil {
+		return err
 	}
+	defer rows.Close()
 
-	logr.Print("[select]:\n", query.String(), ", with values: ", ps)
-	row := db.QueryRow(query.String(), ps...)
-	return row.Scan(fields...)
+	if !rows.Next() {
+		if err := rows.Err(); err != nil {
+			return err
+		}
+		return sql.ErrNoRows
+	}
+	if err := rows.Scan(fields...); err != nil {
+		return err
+	}
+	return rows.Close()
+}
+
+// TODO(ttacon): reuse these as we add other dialects
+func findEntity(db pouch.Executor, i pouch.Findable, rest string, ps []interface{}, logr Logger) error {
+	cols, fields := i.GetAllFields()
+	if len(cols) == 0 || len(fields) == 0 {
+		return errors.New("must provide columns to select from")
+	}
+
+	table := i.Table()
+	if len(table) == 0 {
+		return errors.New("entity is not known to map to any table")
+	}
+
+	var query = builder.NewBuilderString(selectStatement(table, cols))
+	query.WriteString(rest)
+
+	start := time.Now()
+	err := queryRow(db, query.String(), ps, fields)
+	logStatement(logr, "select", table, query.String(), ps, start, rowCount(err), err)
+	if err != nil {
+		return err
+	}
+	snapshot(i, nil)
+	return nil
 }
 
 func createEntity(db pouch.Executor, i pouch.Createable, rest string, logr Logger) error {
@@ -155,21 +308,15 @@ func createEntity(db pouch.Executor, i pouch.Createable, rest string, logr Logge
 		return errors.New("[inserting], there cannot be more columns than values")
 	}
 
-	placeholders := "?"
-	if len(vals) > 1 {
-		placeholders += strings.Repeat(", ?", len(vals)-1)
-	}
-
 	table := i.Table()
 	if len(table) == 0 {
 		return errors.New("this entity is not known to be associated with any table")
 	}
 
-	var query = builder.NewBuilderString("insert into " + table)
-	query.WriteString("(\n  " + strings.Join(cols, ", ") + "\n) values ")
-	query.WriteString("(\n  " + placeholders + "\n)")
-	logr.Print("[create]:\n", query.String(), ", with values: ", vals)
-	res, err := db.Exec(query.String(), vals...)
+	query := insertStatement(table, cols)
+	start := time.Now()
+	res, err := db.Exec(query, vals...)
+	logExec(logr, "create", table, query, vals, start, res, err)
 	if err != nil {
 		return err
 	}
@@ -178,11 +325,22 @@ func createEntity(db pouch.Executor, i pouch.Createable, rest string, logr Logge
 	if err != nil {
 		return err
 	}
-	return i.SetIdentifier(id)
+	if err := i.SetIdentifier(id); err != nil {
+		return err
+	}
+	snapshot(i, nil)
+	return nil
 }
 
-func updateEntity(db pouch.Executor, u pouch.Updateable, rest string, logr Logger) error {
+// updateEntity updates the given columns of an entity, or if none are
+// given, every column that has changed since the entity was last
+// snapshotted (which is all of them for entities that aren't Trackable),
+// as long as it also satisfies the given criterions.
+func updateEntity(db pouch.Executor, u pouch.Updateable, only []string, criterions []constraintPair, strict bool, logr Logger) error {
 	var cols, vals = u.InsertableFields()
+	if len(only) > 0 {
+		cols, vals = only, u.FieldsFor(only)
+	}
 	if len(cols) == 0 || len(vals) == 0 {
 		return errors.New("cannot insert empty entity")
 	}
@@ -195,55 +353,242 @@ func updateEntity(db pouch.Executor, u pouch.Updateable, rest string, logr Logge
 		return errors.New("this entity is not known to be associated with any table")
 	}
 
-	ids, idVals := u.IdentifiableFields()
-	if len(ids) == 0 || len(idVals) == 0 {
-		return errors.New("no identifying information for entity")
+	cs, err := identityConstraints(u)
+	if err != nil {
+		return err
+	}
+	cs = append(cs, criterions...)
+
+	if tracked, ok := u.(pouch.Trackable); ok && len(only) == 0 {
+		if changed, ok := tracked.Changed(cols, vals); ok {
+			if len(changed) == 0 {
+				// nothing to write, though strict updates must still
+				// find the entity
+				if !strict {
+					return nil
+				}
+				return stored(db, u, table, cs, logr)
+			}
+			cols, vals = filterColumns(cols, vals, changed)
+		}
 	}
 
-	var query = builder.NewBuilderString("update " + table + "\nset ")
-	for i, col := range cols {
-		if i > 0 && i < len(cols) {
-			query.WriteString(", ")
+	// versioned entities are only updated if no one else has updated
+	// them since they were read, and their version is bumped
+	versioned, isVersioned := u.(pouch.Versioned)
+	var versionCol string
+	var version int64
+	if isVersioned {
+		versionCol, version = versioned.VersionField()
+		if len(versionCol) == 0 {
+			return errors.New("versioned entity does not name a version column")
 		}
-		query.WriteString(col + " = ?")
+		cols, vals = withoutColumn(cols, vals, versionCol)
+		cols = append(cols, versionCol)
+		vals = append(vals, version+1)
+		cs = append(cs, constraintPair{
+			frag: versionCol + " = ?",
+			vals: []interface{}{version},
+		})
 	}
 
+	where, idVals := joinConstraints(cs)
 	vals = append(vals, idVals...)
-	query.WriteString("\nwhere ")
-	for i, id := range ids {
-		if i > 0 && i < len(ids)-1 {
-			query.WriteString(" AND ")
+	query := memoize(func() string {
+		var query = builder.NewBuilderString("update " + table + "\nset ")
+		for i, col := range cols {
+			if i > 0 {
+				query.WriteString(", ")
+			}
+			query.WriteString(col + " = ?")
+		}
+		query.WriteString("\nwhere " + where)
+		return query.String()
+	}, append([]string{"update", table, where}, cols...)...)
+
+	start := time.Now()
+	res, err := db.Exec(query, vals...)
+	logExec(logr, "update", table, query, vals, start, res, err)
+	if err != nil {
+		return err
+	}
+
+	if !isVersioned {
+		err = matched(res, strict, u)
+	} else if n, aErr := res.RowsAffected(); aErr != nil {
+		err = aErr
+	} else if n == 0 {
+		err = &pouch.ConflictError{Conflicts: []pouch.Updateable{u}}
+	} else {
+		versioned.SetVersion(version + 1)
+	}
+
+	if err == nil {
+		snapshot(u, cols)
+	}
+	return err
+}
+
+// withoutColumn removes the given column, and its value, from the given
+// columns and values.
+func withoutColumn(cols []string, vals []interface{}, col string) ([]string, []interface{}) {
+	var (
+		keptCols []string
+		keptVals []interface{}
+	)
+	for i, c := range cols {
+		if c != col {
+			keptCols = append(keptCols, c)
+			keptVals = append(keptVals, vals[i])
+		}
+	}
+	return keptCols, keptVals
+}
+
+// filterColumns keeps only the given columns, and their values, from
+// the given columns and values.
+func filterColumns(cols []string, vals []interface{}, keep []string) ([]string, []interface{}) {
+	var (
+		keptCols []string
+		keptVals []interface{}
+	)
+	for i, c := range cols {
+		for _, k := range keep {
+			if c == k {
+				keptCols = append(keptCols, c)
+				keptVals = append(keptVals, vals[i])
+				break
+			}
 		}
-		query.WriteString(id + " = ? ")
+	}
+	return keptCols, keptVals
+}
+
+// snapshot records the current values of the given columns (or all of
+// them, if none are given) of a Trackable entity.
+func snapshot(e interface{}, only []string) {
+	tracked, ok := e.(pouch.Trackable)
+	if !ok {
+		return
+	}
+	in, ok := e.(pouch.Insertable)
+	if !ok {
+		return
 	}
 
-	logr.Print("[update]:\n", query.String(), ", with values: ", vals)
-	_, err := db.Exec(query.String(), vals...)
+	cols, vals := in.InsertableFields()
+	if len(only) > 0 {
+		cols, vals = filterColumns(cols, vals, only)
+	}
+	tracked.Snapshot(cols, vals)
+}
+
+// stored returns a *pouch.NotFoundError if no row of the given table
+// satisfies the given constraints, for the given entity.
+func stored(db pouch.Executor, d pouch.Deleteable, table string, cs []constraintPair, logr Logger) error {
+	where, vals := joinConstraints(cs)
+	query := memoize(func() string {
+		return "select 1\nfrom " + table + "\nwhere " + where + "\nlimit 1"
+	}, "exists", table, where)
+
+	var one int
+	start := time.Now()
+	err := queryRow(db, query, vals, []interface{}{&one})
+	logStatement(logr, "select", table, query, vals, start, rowCount(err), err)
+	if err == sql.ErrNoRows {
+		return &pouch.NotFoundError{Missing: []pouch.Deleteable{d}}
+	}
 	return err
 }
 
-func deleteEntity(db pouch.Executor, d pouch.Deleteable, rest string, logr Logger) error {
+// matched returns a *pouch.NotFoundError, when strict, if the statement
+// run for the given entity did not affect any rows.
+func matched(res sql.Result, strict bool, d pouch.Deleteable) error {
+	if !strict {
+		return nil
+	}
+
+	n, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return &pouch.NotFoundError{Missing: []pouch.Deleteable{d}}
+	}
+	return nil
+}
+
+// deleteEntity removes the given entity from its table, unless it is
+// SoftDeleteable, in which case it is only marked as deleted, as long as
+// it also satisfies the given criterions.
+func deleteEntity(db pouch.Executor, d pouch.Deleteable, criterions []constraintPair, strict bool, logr Logger) error {
+	if sd, ok := d.(pouch.SoftDeleteable); ok {
+		return setDeletedAt(db, sd, now(), criterions, strict, logr)
+	}
+	return hardDeleteEntity(db, d, criterions, strict, logr)
+}
+
+func hardDeleteEntity(db pouch.Executor, d pouch.Deleteable, criterions []constraintPair, strict bool, logr Logger) error {
 	table := d.Table()
 	if len(table) == 0 {
 		return errors.New("this entity is not known to be associated with any table")
 	}
 
-	ids, idVals := d.IdentifiableFields()
-	if len(ids) == 0 || len(idVals) == 0 {
-		return errors.New("no identifying information for entity")
+	cs, err := identityConstraints(d)
+	if err != nil {
+		return err
 	}
+	where, idVals := joinConstraints(append(cs, criterions...))
 
-	var query = builder.NewBuilderString("delete\nfrom " + table + "\nwhere ")
-	for i, id := range ids {
-		if i > 0 && i < len(ids) {
-			query.WriteString(", ")
-		}
-		query.WriteString(id + " = ?")
+	query := memoize(func() string {
+		return "delete\nfrom " + table + "\nwhere " + where
+	}, "delete", table, where)
+
+	start := time.Now()
+	res, err := db.Exec(query, idVals...)
+	logExec(logr, "delete", table, query, idVals, start, res, err)
+	if err != nil {
+		return err
 	}
+	return matched(res, strict, d)
+}
 
-	logr.Print("[delete]:\n", query.String(), ", with values: ", idVals)
-	_, err := db.Exec(query.String(), idVals...)
-	return err
+// setDeletedAt sets the deleted at column of the given entity, a nil
+// time restores the entity. Entities that are already soft deleted
+// aren't deleted again, so they keep the time they were deleted at.
+func setDeletedAt(db pouch.Executor, sd pouch.SoftDeleteable, at interface{}, criterions []constraintPair, strict bool, logr Logger) error {
+	table := sd.Table()
+	if len(table) == 0 {
+		return errors.New("this entity is not known to be associated with any table")
+	}
+
+	col := sd.DeletedAtColumn()
+	if len(col) == 0 {
+		return errors.New("entity does not name a deleted at column")
+	}
+
+	cs, err := identityConstraints(sd)
+	if err != nil {
+		return err
+	}
+	cs = append(cs, criterions...)
+	if at != nil {
+		cs = append(cs, constraintPair{frag: col + " is null"})
+	}
+	where, idVals := joinConstraints(cs)
+
+	query := memoize(func() string {
+		return "update " + table + "\nset " + col + " = ?\nwhere " + where
+	}, "soft delete", table, col, where)
+
+	vals := append([]interface{}{at}, idVals...)
+	start := time.Now()
+	res, err := db.Exec(query, vals...)
+	logExec(logr, "soft delete", table, query, vals, start, res, err)
+	if err != nil {
+		return err
+	}
+	return matched(res, strict, sd)
 }
 
 ////////// SQL pouch.Query implementation //////////
@@ -255,7 +600,19 @@ type sqlQuery struct {
 	constraints  []constraintPair
 	limit        int
 	offset       int
-	l            Logger
+	deleted      deletedScope
+	l            *pouchLogger
+	opts         options
+
+	// whether or not bulk writes may run without constraints
+	unconstrained bool
+	// relations to retrieve after FindEntities
+	preloads []string
+	// keyset pagination cursors
+	after, before pouch.Cursor
+	// whether or not the tables' default scopes are ignored
+	unscoped bool
+	ctx      context.Context
 }
 
 type constraintPair struct {
@@ -263,111 +620,526 @@ type constraintPair struct {
 	vals []interface{}
 }
 
+// deletedScope determines which soft deleted entities a query sees.
+type deletedScope int
+
+const (
+	excludeDeleted deletedScope = iota
+	includeDeleted
+	onlyDeleted
+)
+
 func (s *sqlQuery) Find(i pouch.Findable) error {
-	rest, vals := buildConstraints(s)
-	return findEntity(s.db, i, rest, vals, s.l)
+	// a query with constraints finds the entity matching them,
+	// otherwise the entity identifies itself
+	var cs = s.criterions(i)
+	if len(s.constraints) == 0 {
+		ids, err := identityConstraints(i)
+		if err != nil {
+			return err
+		}
+		cs = append(ids, cs...)
+	}
+	rest, vals := s.clauses(cs, i)
+	return findEntity(s.executor("Find", i.Table()), i, rest, vals, s.logr())
 }
 
 func (s *sqlQuery) FindAll(fs []pouch.Findable) error {
-	rest, ps := buildConstraints(s)
-	return findAll(s.db, fs, rest, ps, s.l)
+	return findAll(s, fs)
 }
 
 func (s *sqlQuery) Create(i pouch.Createable) error {
-	rest, _ := buildConstraints(s)
-	return createEntity(s.db, i, rest, s.l)
+	return createEntity(s.executor("Create", i.Table()), i, "", s.logr())
 }
 
 func (s *sqlQuery) CreateAll(cs []pouch.Createable) error {
-	return createAll(s.db, cs, s.l)
+	return createAll(s.executor("CreateAll", firstTable(cs)), cs, s.logr())
 }
 
 func (s *sqlQuery) Update(u pouch.Updateable) error {
-	rest, _ := buildConstraints(s)
-	return updateEntity(s.db, u, rest, s.l)
+	return updateEntity(s.updater("Update", u), u, nil, s.criterions(u), s.opts.strict, s.logr())
+}
+
+func (s *sqlQuery) UpdateColumns(u pouch.Updateable, cols ...string) error {
+	if len(cols) == 0 {
+		return errors.New("no columns to update")
+	}
+	return updateEntity(s.updater("UpdateColumns", u), u, cols, s.criterions(u), s.opts.strict, s.logr())
 }
 
 func (s *sqlQuery) UpdateAll(us []pouch.Updateable) error {
-	// TODO(ttaco): do it
-	return nil
+	return updateAll(s.updater("UpdateAll", us...), us, s.criterions, s.opts.strict, s.logr())
 }
 
 func (s *sqlQuery) Delete(i pouch.Deleteable) error {
-	rest, _ := buildConstraints(s)
-	return deleteEntity(s.db, i, rest, s.l)
+	return deleteEntity(s.executor("Delete", i.Table()), i, s.criterions(i), s.opts.strict, s.logr())
 }
 
 func (s *sqlQuery) DeleteAll(ds []pouch.Deleteable) error {
-	return deleteAll(s.db, ds, s.l)
+	return deleteAll(s.executor("DeleteAll", firstTable(ds)), ds, s.criterions, s.opts.strict, s.logr())
+}
+
+func (s *sqlQuery) HardDelete(d pouch.Deleteable) error {
+	return hardDeleteEntity(s.executor("HardDelete", d.Table()), d, s.criterions(d), s.opts.strict, s.logr())
+}
+
+func (s *sqlQuery) Restore(sd pouch.SoftDeleteable) error {
+	return setDeletedAt(s.executor("Restore", sd.Table()), sd, nil, s.criterions(sd), s.opts.strict, s.logr())
+}
+
+func (s *sqlQuery) UpdateWhere(t pouch.Tableable, assignments map[string]interface{}) (int64, error) {
+	if q := s.scoped(t); q != nil {
+		return q.UpdateWhere(t, assignments)
+	}
+	if len(assignments) == 0 {
+		return 0, errors.New("no columns to update")
+	}
+
+	where, whereVals, err := s.bulkWhere(t)
+	if err != nil {
+		return 0, err
+	}
+
+	var cols = make([]string, 0, len(assignments))
+	for col := range assignments {
+		cols = append(cols, col)
+	}
+	// keep the generated statement stable
+	sort.Strings(cols)
+
+	var vals = make([]interface{}, 0, len(cols)+len(whereVals))
+	var query = builder.NewBuilderString("update " + t.Table() + "\nset ")
+	for i, col := range cols {
+		if i > 0 {
+			query.WriteString(", ")
+		}
+		query.WriteString(col + " = ?")
+		vals = append(vals, assignments[col])
+	}
+	query.WriteString("\n" + where)
+	vals = append(vals, whereVals...)
+
+	start := time.Now()
+	res, err := s.executor("UpdateWhere", t.Table()).Exec(query.String(), vals...)
+	logExec(s.logr(), "update where", t.Table(), query.String(), vals, start, res, err)
+	if err != nil {
+		return 0, err
+	}
+	return res.RowsAffected()
+}
+
+// DeleteWhere soft deletes the matching entities if the Tableable is
+// SoftDeleteable.
+func (s *sqlQuery) DeleteWhere(t pouch.Tableable) (int64, error) {
+	if q := s.scoped(t); q != nil {
+		return q.DeleteWhere(t)
+	}
+	if sd, ok := t.(pouch.SoftDeleteable); ok {
+		return s.UpdateWhere(t, map[string]interface{}{
+			sd.DeletedAtColumn(): now(),
+		})
+	}
+
+	where, vals, err := s.bulkWhere(t)
+	if err != nil {
+		return 0, err
+	}
+
+	var query = builder.NewBuilderString("delete\nfrom " + t.Table() + "\n")
+	query.WriteString(where)
+
+	start := time.Now()
+	res, err := s.executor("DeleteWhere", t.Table()).Exec(query.String(), vals...)
+	logExec(s.logr(), "delete where", t.Table(), query.String(), vals, start, res, err)
+	if err != nil {
+		return 0, err
+	}
+	return res.RowsAffected()
+}
+
+func (s *sqlQuery) AllowUnconstrained() pouch.Query {
+	c := s.clone()
+	c.unconstrained = true
+	return c
+}
+
+// bulkWhere builds the where clause for a bulk update or delete of the
+// given table.
+func (s *sqlQuery) bulkWhere(t pouch.Tableable) (string, []interface{}, error) {
+	if len(t.Table()) == 0 {
+		return "", nil, errors.New("this entity is not known to be associated with any table")
+	}
+	if len(s.constraints) == 0 && !s.unconstrained {
+		return "", nil, pouch.ErrUnconstrained
+	}
+	if len(s.groupBySpecs) > 0 || len(s.orderBySpecs) > 0 || s.limit > 0 || s.offset > 0 {
+		return "", nil, errors.New("bulk updates and deletes only support where constraints")
+	}
+
+	where, vals := s.clauses(s.constraints, t)
+	return where, vals, nil
+}
+
+func (s *sqlQuery) Clone() pouch.Query {
+	return s.clone()
+}
+
+// clone copies the query, capping its slices so that appending to the
+// copy's never writes to the original's.
+func (s *sqlQuery) clone() *sqlQuery {
+	c := *s
+	c.groupBySpecs = c.groupBySpecs[:len(c.groupBySpecs):len(c.groupBySpecs)]
+	c.orderBySpecs = c.orderBySpecs[:len(c.orderBySpecs):len(c.orderBySpecs)]
+	c.constraints = c.constraints[:len(c.constraints):len(c.constraints)]
+	c.preloads = c.preloads[:len(c.preloads):len(c.preloads)]
+	return &c
 }
 
 func (s *sqlQuery) GroupBy(spec string) pouch.Query {
-	s.groupBySpecs = append(s.groupBySpecs, spec)
-	return s
+	c := s.clone()
+	c.groupBySpecs = append(c.groupBySpecs, spec)
+	return c
 }
 
 func (s *sqlQuery) OrderBy(spec string) pouch.Query {
-	s.orderBySpecs = append(s.orderBySpecs, spec)
-	return s
+	c := s.clone()
+	c.orderBySpecs = append(c.orderBySpecs, spec)
+	return c
 }
 
 func (s *sqlQuery) Where(frag string, vals ...interface{}) pouch.Query {
-	s.constraints = append(s.constraints, constraintPair{
+	c := s.clone()
+	c.constraints = append(c.constraints, constraintPair{
 		frag: frag,
 		vals: vals,
 	})
-	return s
+	return c
 }
 
 func (s *sqlQuery) Limit(lim int) pouch.Query {
-	s.limit = lim
-	return s
+	c := s.clone()
+	c.limit = lim
+	return c
 }
 
 func (s *sqlQuery) Offset(off int) pouch.Query {
-	s.offset = off
-	return s
+	c := s.clone()
+	c.offset = off
+	return c
+}
+
+func (s *sqlQuery) WithDeleted() pouch.Query {
+	c := s.clone()
+	c.deleted = includeDeleted
+	return c
+}
+
+func (s *sqlQuery) OnlyDeleted() pouch.Query {
+	c := s.clone()
+	c.deleted = onlyDeleted
+	return c
+}
+
+func (s *sqlQuery) Preload(relations ...string) pouch.Query {
+	c := s.clone()
+	c.preloads = append(c.preloads, relations...)
+	return c
+}
+
+func (s *sqlQuery) After(c pouch.Cursor) pouch.Query {
+	q := s.clone()
+	q.after = c
+	return q
+}
+
+func (s *sqlQuery) Before(c pouch.Cursor) pouch.Query {
+	q := s.clone()
+	q.before = c
+	return q
+}
+
+func (s *sqlQuery) Scopes(scopes ...pouch.Scope) pouch.Query {
+	var q pouch.Query = s.clone()
+	for _, scope := range scopes {
+		q = scope(q)
+	}
+	return q
+}
+
+func (s *sqlQuery) Unscoped() pouch.Query {
+	q := s.clone()
+	q.unscoped = true
+	return q
+}
+
+func (s *sqlQuery) WithContext(ctx context.Context) pouch.Query {
+	q := s.clone()
+	q.ctx = ctx
+	return q
+}
+
+// logr returns the Logger the query's statements are logged through,
+// which also adds them to the span in the query's context, if any.
+func (s *sqlQuery) logr() Logger {
+	if span, ok := pouch.SpanFromContext(s.Context()); ok {
+		return spanLogger{Logger: s.l, span: span}
+	}
+	return s.l
+}
+
+func (s *sqlQuery) Context() context.Context {
+	if s.ctx == nil {
+		return context.Background()
+	}
+	return s.ctx
+}
+
+// scoped returns the query with the default scopes of the given table
+// applied, to run in its stead, or nil if there are none to apply.
+func (s *sqlQuery) scoped(t pouch.Tableable) pouch.Query {
+	scopes := s.opts.scopes[t.Table()]
+	if s.unscoped || len(scopes) == 0 {
+		return nil
+	}
+	return s.Unscoped().Scopes(scopes...)
+}
+
+// criterions returns the constraints the entities of the given table
+// must satisfy to be found, written or deleted by their identity: the
+// query's own, and those of the table's default scopes.
+func (s *sqlQuery) criterions(t pouch.Tableable) []constraintPair {
+	if q, ok := s.scoped(t).(*sqlQuery); ok {
+		return q.constraints
+	}
+	return s.constraints
 }
 
 func (s *sqlQuery) FindEntities(template pouch.Findable, res *[]pouch.Findable) error {
-	rest, ps := buildConstraints(s)
-	return findEntities(s.db, template, res, rest, ps, s.l)
+	if q := s.scoped(template); q != nil {
+		return q.FindEntities(template, res)
+	}
+	return s.findEntities(template, res, false)
 }
 
-//TODO(ttacon): add HAVING
-func buildConstraints(s *sqlQuery) (string, []interface{}) {
-	var constraints = builder.NewBuilder(nil)
-	var vals []interface{}
-	for i, constraint := range s.constraints {
-		if i > 0 && i < len(s.constraints)-1 {
-			// TODO(ttacon): decent way to specify AND vs OR
-			constraints.WriteString(" AND ")
+func (s *sqlQuery) FindPage(template pouch.Findable, res *[]pouch.Findable) (pouch.Cursor, pouch.Cursor, error) {
+	if q := s.scoped(template); q != nil {
+		return q.FindPage(template, res)
+	}
+	var found = len(*res)
+	if err := s.findEntities(template, res, true); err != nil {
+		return "", "", err
+	}
+	order := pouch.KeysetOrder(s.orderBySpecs, template)
+	return pouch.PageCursors((*res)[found:], order, s.after, s.before, s.limit)
+}
+
+// findEntities finds the entities satisfying the query, in keyset order
+// if asked to or if the query has a cursor, then preloads their relations.
+func (s *sqlQuery) findEntities(template pouch.Findable, res *[]pouch.Findable, keyset bool) error {
+	var (
+		found    = len(*res)
+		q        = s
+		cs       = s.constraints
+		backward = len(s.before) > 0 && len(s.after) == 0
+	)
+	if keyset || len(s.after) > 0 || len(s.before) > 0 {
+		order := pouch.KeysetOrder(s.orderBySpecs, template)
+		for _, c := range []struct {
+			cursor pouch.Cursor
+			after  bool
+		}{{s.after, true}, {s.before, false}} {
+			if len(c.cursor) == 0 {
+				continue
+			}
+			kc, err := keysetConstraint(order, c.cursor, c.after)
+			if err != nil {
+				return err
+			}
+			cs = append(cs[:len(cs):len(cs)], kc)
+		}
+
+		// paging backward reads the results closest to the cursor
+		// first, so they come in reverse
+		k := *s
+		k.orderBySpecs = orderSpecs(order, backward)
+		q = &k
+	}
+
+	rest, ps := q.clauses(cs, template)
+	var op = "FindEntities"
+	if keyset {
+		op = "FindPage"
+	}
+	if err := findEntities(s.executor(op, template.Table()), template, res, rest, ps, s.logr()); err != nil {
+		return err
+	}
+	if backward {
+		page := (*res)[found:]
+		for i, j := 0, len(page)-1; i < j; i, j = i+1, j-1 {
+			page[i], page[j] = page[j], page[i]
+		}
+	}
+	if len(s.preloads) == 0 {
+		return nil
+	}
+
+	// relations are found through the pouch, free of this query's criterions
+	var related = &sqlPouch{
+		db:   s.db,
+		l:    s.l,
+		opts: s.opts,
+	}
+	return pouch.Preload(related, (*res)[found:], s.preloads...)
+}
+
+// keysetConstraint returns the constraint for the results after (or
+// before) the given cursor in the given order. It's a row-value
+// comparison, unless the columns are ordered in different directions.
+func keysetConstraint(order []pouch.OrderColumn, c pouch.Cursor, after bool) (constraintPair, error) {
+	vals, err := c.Values()
+	if err != nil {
+		return constraintPair{}, err
+	}
+	if len(vals) != len(order) {
+		return constraintPair{}, errors.New("cursor does not match the query's order")
+	}
+
+	op := func(o pouch.OrderColumn) string {
+		if after != o.Desc {
+			return " > ?"
 		}
-		constraints.WriteString(constraint.frag)
-		constraints.WriteString("\n")
-		vals = append(vals, constraint.vals...)
+		return " < ?"
 	}
 
-	for i, group := range s.groupBySpecs {
-		if i == 0 {
-			constraints.WriteString("group by ")
+	var uniform = true
+	for _, o := range order {
+		uniform = uniform && o.Desc == order[0].Desc
+	}
+	if len(order) == 1 {
+		return constraintPair{frag: order[0].Column + op(order[0]), vals: vals}, nil
+	}
+	if uniform {
+		var cols, marks = make([]string, len(order)), make([]string, len(order))
+		for i, o := range order {
+			cols[i], marks[i] = o.Column, "?"
 		}
-		if i > 0 && i < len(s.groupBySpecs)-1 {
-			constraints.WriteString(", ")
+		return constraintPair{
+			frag: "(" + strings.Join(cols, ", ") + ")" +
+				strings.TrimSuffix(op(order[0]), "?") +
+				"(" + strings.Join(marks, ", ") + ")",
+			vals: vals,
+		}, nil
+	}
+
+	// (a > ?) OR (a = ? AND b < ?) OR ...
+	var (
+		ors    = make([]string, len(order))
+		orVals []interface{}
+	)
+	for i, o := range order {
+		var ands []string
+		for j := 0; j < i; j++ {
+			ands = append(ands, order[j].Column+" = ?")
+			orVals = append(orVals, vals[j])
 		}
-		constraints.WriteString(group)
-		constraints.WriteString("\n")
+		ands = append(ands, o.Column+op(o))
+		orVals = append(orVals, vals[i])
+		ors[i] = "(" + strings.Join(ands, " AND ") + ")"
 	}
+	return constraintPair{frag: "(" + strings.Join(ors, " OR ") + ")", vals: orVals}, nil
+}
 
-	for i, order := range s.orderBySpecs {
-		if i == 0 {
-			constraints.WriteString("order by ")
+// orderSpecs turns the given order back into order by specs, reversed
+// if need be.
+func orderSpecs(order []pouch.OrderColumn, reverse bool) []string {
+	var specs = make([]string, len(order))
+	for i, o := range order {
+		specs[i] = o.Column
+		if o.Desc != reverse {
+			specs[i] += " desc"
 		}
-		if i > 0 && i < len(s.orderBySpecs)-1 {
-			constraints.WriteString(", ")
+	}
+	return specs
+}
+
+// identityConstraints returns the constraints that uniquely identify
+// the given entity.
+func identityConstraints(i pouch.Identifiable) ([]constraintPair, error) {
+	ids, vals := i.IdentifiableFields()
+	if len(ids) == 0 || len(vals) == 0 {
+		return nil, errors.New("no identifying information for entity")
+	}
+	if len(ids) != len(vals) {
+		return nil, errors.New("entity has a different number of identifying columns and values")
+	}
+
+	var cs = make([]constraintPair, len(ids))
+	for i, id := range ids {
+		cs[i] = constraintPair{
+			frag: id + " = ?",
+			vals: []interface{}{vals[i]},
 		}
-		constraints.WriteString(order)
-		constraints.WriteString("\n")
+	}
+	return cs, nil
+}
+
+// softDeleteConstraint returns the constraint that hides (or exclusively
+// shows) soft deleted entities for the given table, if any.
+func softDeleteConstraint(scope deletedScope, t pouch.Tableable) (constraintPair, bool) {
+	sd, ok := t.(interface {
+		DeletedAtColumn() string
+	})
+	if !ok || len(sd.DeletedAtColumn()) == 0 {
+		return constraintPair{}, false
+	}
+
+	switch scope {
+	case excludeDeleted:
+		return constraintPair{frag: sd.DeletedAtColumn() + " is null"}, true
+	case onlyDeleted:
+		return constraintPair{frag: sd.DeletedAtColumn() + " is not null"}, true
+	}
+	return constraintPair{}, false
+}
+
+// joinConstraints ANDs together the given constraints.
+func joinConstraints(cs []constraintPair) (string, []interface{}) {
+	var (
+		frags = make([]string, len(cs))
+		vals  []interface{}
+	)
+	for i, c := range cs {
+		frags[i] = c.frag
+		vals = append(vals, c.vals...)
+	}
+	// TODO(ttacon): decent way to specify AND vs OR
+	return strings.Join(frags, " AND "), vals
+}
+
+// clauses builds everything in a select statement for the given table
+// that comes after its from clause: the where clause (from the given
+// constraints and the query's soft delete scope) and the query's group
+// by, order by and limit clauses.
+//TODO(ttacon): add HAVING
+func (s *sqlQuery) clauses(cs []constraintPair, t pouch.Tableable) (string, []interface{}) {
+	if c, ok := softDeleteConstraint(s.deleted, t); ok {
+		cs = append(cs[:len(cs):len(cs)], c)
+	}
+
+	var constraints = builder.NewBuilder(nil)
+	var vals []interface{}
+	if len(cs) > 0 {
+		var where string
+		where, vals = joinConstraints(cs)
+		constraints.WriteString("where " + where + "\n")
+	}
+
+	if len(s.groupBySpecs) > 0 {
+		constraints.WriteString("group by " + strings.Join(s.groupBySpecs, ", ") + "\n")
+	}
+
+	if len(s.orderBySpecs) > 0 {
+		constraints.WriteString("order by " + strings.Join(s.orderBySpecs, ", ") + "\n")
 	}
 
 	if s.limit > 0 {
@@ -381,7 +1153,7 @@ func buildConstraints(s *sqlQuery) (string, []interface{}) {
 }
 
 ////////// *All functions //////////
-func findAll(db pouch.Executor, fs []pouch.Findable, rest string, ps []interface{}, logr Logger) error {
+func findAll(s *sqlQuery, fs []pouch.Findable) error {
 	// it assumes fs is full of entities who know their identifying info
 	if len(fs) == 0 {
 		// how's this for a cryptic error lol
@@ -389,47 +1161,13 @@ func findAll(db pouch.Executor, fs []pouch.Findable, rest string, ps []interface
 	}
 
 	for _, i := range fs {
-		table := i.Table()
-		if len(table) == 0 {
-			return errors.New("entity is not known to map to any table")
-		}
-
-		cols, fields := i.GetAllFields()
-		if len(cols) == 0 || len(fields) == 0 {
-			return errors.New("must provide columns to select from")
-		}
-
-		var query = builder.NewBuilderString("select ")
-		for i, col := range cols {
-			if i > 0 {
-				query.WriteString(",\n  ")
-			}
-			query.WriteString(col)
-		}
-		query.WriteString("\nfrom " + table + "\n")
-
-		ids, vals := i.IdentifiableFields()
-		if len(ids) == 0 || len(vals) == 0 {
-			return errors.New("no identifying information for entity")
-		}
-
-		ps = append(vals, ps...)
-		query.WriteString("where ")
-		for i, id := range ids {
-			if i > 0 && i < len(ids)-1 {
-				query.WriteString(" AND ")
-			}
-			query.WriteString(id + " = ? ")
-		}
-
-		if len(rest) > 0 {
-			query.WriteString(" AND " + rest)
+		cs, err := identityConstraints(i)
+		if err != nil {
+			return err
 		}
 
-		logr.Print("[find]:\n", query.String(), ", vals: ", ps)
-		row := db.QueryRow(query.String(), ps...)
-
-		if err := row.Scan(fields...); err != nil {
+		rest, ps := s.clauses(append(cs, s.criterions(i)...), i)
+		if err := findEntity(s.executor("FindAll", i.Table()), i, rest, ps, s.logr()); err != nil {
 			return err
 		}
 	}
@@ -453,21 +1191,15 @@ func createAll(db pouch.Executor, cs []pouch.Createable, logr Logger) error {
 			return errors.New("[inserting], there cannot be more columns than values")
 		}
 
-		placeholders := "?"
-		if len(vals) > 1 {
-			placeholders += strings.Repeat(", ?", len(vals)-1)
-		}
-
 		table := i.Table()
 		if len(table) == 0 {
 			return errors.New("this entity is not known to be associated with any table")
 		}
 
-		var query = builder.NewBuilderString("insert into " + table)
-		query.WriteString("(\n" + strings.Join(cols, ", ") + "\n) values ")
-		query.WriteString("(\n" + placeholders + "\n)")
-		logr.Print("[create]:\n", query.String(), ", vals: ", vals)
-		res, err := db.Exec(query.String(), vals...)
+		query := insertStatement(table, cols)
+		start := time.Now()
+		res, err := db.Exec(query, vals...)
+		logExec(logr, "create", table, query, vals, start, res, err)
 		if err != nil {
 			return err
 		}
@@ -480,40 +1212,66 @@ func createAll(db pouch.Executor, cs []pouch.Createable, logr Logger) error {
 		if err != nil {
 			return err
 		}
+		snapshot(i, nil)
 	}
 	return nil
 }
 
-func deleteAll(db pouch.Executor, ds []pouch.Deleteable, logr Logger) error {
-	if len(ds) == 0 {
-		return errors.New("[deleteAll] no entities to delete")
+// updateAll updates every given entity, versioned entities that conflict
+// (or, when strict, entities that are missing) do not stop the rest
+// from being updated, instead they are all reported in a single
+// *pouch.ConflictError (or *pouch.NotFoundError).
+func updateAll(db pouch.Executor, us []pouch.Updateable, criterions func(pouch.Tableable) []constraintPair, strict bool, logr Logger) error {
+	if len(us) == 0 {
+		return errors.New("[updateAll] no entities to update")
 	}
 
-	for _, d := range ds {
-		table := d.Table()
-		if len(table) == 0 {
-			return errors.New("this entity is not known to be associated with any table")
+	var (
+		conflicts []pouch.Updateable
+		missing   []pouch.Deleteable
+	)
+	for _, u := range us {
+		err := updateEntity(db, u, nil, criterions(u), strict, logr)
+		switch e := err.(type) {
+		case nil:
+		case *pouch.ConflictError:
+			conflicts = append(conflicts, e.Conflicts...)
+		case *pouch.NotFoundError:
+			missing = append(missing, e.Missing...)
+		default:
+			return err
 		}
+	}
 
-		ids, idVals := d.IdentifiableFields()
-		if len(ids) == 0 || len(idVals) == 0 {
-			return errors.New("no identifying information for entity")
-		}
+	if len(conflicts) > 0 {
+		return &pouch.ConflictError{Conflicts: conflicts}
+	}
+	if len(missing) > 0 {
+		return &pouch.NotFoundError{Missing: missing}
+	}
+	return nil
+}
 
-		var query = builder.NewBuilderString("delete\nfrom " + table + "\nwhere ")
-		for i, id := range ids {
-			if i > 0 && i < len(ids) {
-				query.WriteString(", ")
-			}
-			query.WriteString(id + " = ?")
-		}
+func deleteAll(db pouch.Executor, ds []pouch.Deleteable, criterions func(pouch.Tableable) []constraintPair, strict bool, logr Logger) error {
+	if len(ds) == 0 {
+		return errors.New("[deleteAll] no entities to delete")
+	}
 
-		logr.Print("[delete]:\n", query.String(), ", vals: ", idVals)
-		_, err := db.Exec(query.String(), idVals...)
+	var missing []pouch.Deleteable
+	for _, d := range ds {
+		err := deleteEntity(db, d, criterions(d), strict, logr)
+		if nErr, ok := err.(*pouch.NotFoundError); ok {
+			missing = append(missing, nErr.Missing...)
+			continue
+		}
 		if err != nil {
 			return err
 		}
 	}
+
+	if len(missing) > 0 {
+		return &pouch.NotFoundError{Missing: missing}
+	}
 	return nil
 }
 
@@ -524,7 +1282,7 @@ func findEntities(
 	fs *[]pouch.Findable,
 	rest string,
 	ps []interface{},
-	logr Logger) error {
+	logr Logger) (err error) {
 
 	table := example.Table()
 	if len(table) == 0 {
@@ -536,24 +1294,22 @@ func findEntities(
 		return errors.New("must provide columns to select from")
 	}
 
-	var query = builder.NewBuilderString("select ")
-	for i, col := range cols {
-		if i > 0 {
-			query.WriteString(",\n  ")
-		}
-		query.WriteString(col)
-	}
-	query.WriteString("\nfrom " + table + "\n")
+	var query = builder.NewBuilderString(selectStatement(table, cols))
+	query.WriteString(rest)
 
-	if len(rest) > 0 {
-		query.WriteString("WHERE " + rest)
-	}
+	var (
+		start = time.Now()
+		found int64
+	)
+	defer func() {
+		logStatement(logr, "find", table, query.String(), ps, start, found, err)
+	}()
 
-	logr.Print("[find]\n ", query.String(), ", vals: ", ps)
 	rows, err := db.Query(query.String(), ps...)
 	if err != nil {
 		return err
 	}
+	defer rows.Close()
 
 	for rows.Next() {
 		cop := example.FindableCopy()
@@ -562,7 +1318,9 @@ func findEntities(
 		if err != nil {
 			return err
 		}
+		snapshot(cop, nil)
 		*fs = append(*fs, cop)
+		found++
 	}
 	return rows.Err()
 }
@@ -0,0 +1,157 @@
+package impl
+
+import (
+	"strings"
+	"time"
+
+	"github.com/ttacon/pouch"
+)
+
+// An Option configures a pouch created by SQLPouch or NewDynamicPouch.
+type Option func(*options)
+
+type options struct {
+	strict bool
+	// whether RawQuery skips columns its template doesn't know
+	ignoreUnknown bool
+	// scopes applied to every query of a table, by table
+	scopes map[string][]pouch.Scope
+	logger Logger
+	// sensitive columns, lower cased, by table
+	redacted map[string]map[string]bool
+	slow     *slowQueries
+	// what every statement is passed through, see Intercept
+	interceptors []Interceptor
+	// how many prepared statements are kept open
+	prepare int
+	// how failed statements are retried, see Retry
+	retry *pouch.RetryPolicy
+}
+
+func newOptions(opts []Option) options {
+	var o options
+	for _, opt := range opts {
+		opt(&o)
+	}
+	return o
+}
+
+// logr returns the Logger the pouch was given, or the default one.
+func (o options) logr() Logger {
+	if o.logger == nil {
+		return defaultLogger()
+	}
+	return o.logger
+}
+
+// WithLogger makes the pouch log through the given Logger, see
+// NewLogger and SlogLogger.
+func WithLogger(l Logger) Option {
+	return func(o *options) {
+		o.logger = l
+	}
+}
+
+// Redact marks columns of the given table as sensitive, so that the
+// values bound to them are logged as <redacted>, i.e.
+//
+//	Redact("User", "Password", "Email")
+//
+// Statements are still logged with their shape and timing. Values
+// bound in statements on the table whose column can't be told (i.e. ones
+// compared to an expression, or to a row of columns) are redacted too,
+// as are all the values bound in hand-written statements (see RawQuery),
+// which may join any table.
+func Redact(table string, cols ...string) Option {
+	return func(o *options) {
+		if o.redacted == nil {
+			o.redacted = make(map[string]map[string]bool)
+		}
+		if o.redacted[table] == nil {
+			o.redacted[table] = make(map[string]bool)
+		}
+		for _, col := range cols {
+			o.redacted[table][strings.ToLower(col)] = true
+		}
+	}
+}
+
+// SlowQueryThreshold makes the pouch log the statements that take longer
+// than the given duration as slow, at WARN level, with a Fingerprint of
+// the statement that statements which only differ by their values share.
+func SlowQueryThreshold(d time.Duration) Option {
+	return func(o *options) {
+		o.slowQueries().threshold = d
+	}
+}
+
+// ExplainSlowQueries makes the pouch run EXPLAIN on slow statements (see
+// SlowQueryThreshold) through the given Executor, which should not be
+// the pouch's own so that explaining doesn't join its transactions, and
+// attach the plan to the Entry handed to OnSlowQuery (statements are
+// only explained for it). At most one statement is explained every given
+// interval; explaining happens in the background, so the slow
+// statement's operation doesn't wait for it.
+func ExplainSlowQueries(db pouch.Executor, every time.Duration) Option {
+	return func(o *options) {
+		o.slowQueries().explain = db
+		o.slowQueries().every = every
+	}
+}
+
+// OnSlowQuery makes the pouch call the given func with the Entry of
+// every slow statement (see SlowQueryThreshold), after it was logged.
+// Statements that are explained (see ExplainSlowQueries) are handed over
+// from another goroutine once they are, so the func may be called
+// concurrently.
+func OnSlowQuery(f func(e *Entry)) Option {
+	return func(o *options) {
+		o.slowQueries().notify = f
+	}
+}
+
+func (o *options) slowQueries() *slowQueries {
+	if o.slow == nil {
+		o.slow = &slowQueries{}
+	}
+	return o.slow
+}
+
+// Strict makes Update, Delete, HardDelete and Restore (and UpdateAll and
+// DeleteAll) return a *pouch.NotFoundError for every entity whose identity
+// matched nothing in the backing storage, instead of quietly succeeding.
+// Updates of Trackable entities that haven't changed, which have nothing
+// to write, look for the entity instead.
+//
+// NOTE: by default MySQL reports the number of rows an update changed,
+// not the number it matched, so an update that doesn't change anything
+// looks like a missing entity. When using github.com/go-sql-driver/mysql,
+// connect with clientFoundRows=true to avoid this.
+func Strict() Option {
+	return func(o *options) {
+		o.strict = true
+	}
+}
+
+// IgnoreUnknownColumns makes RawQuery skip the columns of its results
+// that the template entity has no field for, instead of erroring out.
+func IgnoreUnknownColumns() Option {
+	return func(o *options) {
+		o.ignoreUnknown = true
+	}
+}
+
+// DefaultScopes registers scopes that every query finding or writing
+// entities of the given table applies, unless it is Unscoped. Queries
+// that address entities by their identity (Find, Update, Delete and the
+// like) only apply the criterions of the scopes, so an entity the scopes
+// exclude is not found, nor written. Dynamic pouches cannot apply them at
+// all, and refuse to run the queries that should (see NewDynamicPouch).
+func DefaultScopes(table string, scopes ...pouch.Scope) Option {
+	return func(o *options) {
+		if o.scopes == nil {
+			o.scopes = make(map[string][]pouch.Scope)
+		}
+		o.scopes[table] = append(o.scopes[table], scopes...)
+	}
+}
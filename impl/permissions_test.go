@@ -0,0 +1,71 @@
+package impl
+
+import (
+	"database/sql/driver"
+	"strings"
+	"testing"
+
+	"github.com/ttacon/pouch"
+)
+
+func Test_permissionViews(t *testing.T) {
+	db, fake := newFakeDB()
+	fake.columns = []string{"ID", "TenantID", "Body"}
+
+	ro := pouch.ReadOnly(SQLPouch(db))
+	var notes []pouch.Findable
+	if err := ro.Where("Body = ?", "hi").FindEntities(&Note{}, &notes); err != nil {
+		t.Fatal("a read only view should read, was: ", err)
+	}
+	err := ro.Delete(&Note{ID: 1})
+	perr, ok := err.(*pouch.PermissionError)
+	if !ok || perr.Table != "Note" || perr.Op != pouch.OpDelete {
+		t.Fatal("a read only view should refuse to delete, was: ", err)
+	}
+	if err.Error() != "permission denied: cannot delete Note" {
+		t.Error("unexpected permission error: ", err)
+	}
+
+	wo := pouch.WriteOnly(SQLPouch(db))
+	var ran = len(fake.all())
+	if err := wo.Offset(0).FindEntities(&Note{}, &notes); err == nil {
+		t.Error("a write only view should refuse to find entities")
+	}
+	if _, err := wo.Where("ID = ?", 1).DeleteWhere(&Note{}); err == nil {
+		t.Error("a write only view should refuse to delete")
+	}
+	if len(fake.all()) != ran {
+		t.Error("refused operations should not reach the database, ran: ", fake.last().query)
+	}
+
+	p := pouch.WithPolicy(SQLPouch(db), pouch.Permissions{
+		"*":    pouch.OpRead,
+		"Note": pouch.OpCreate,
+	})
+	if err := p.Create(&Note{Body: "hi"}); err != nil {
+		t.Error("creating notes should be allowed, was: ", err)
+	}
+	if err := p.Create(&Plant{Name: "fern"}); err == nil {
+		t.Error("creating plants should not be allowed")
+	}
+	if err := p.Update(&Note{ID: 1}); err == nil {
+		t.Error("updating notes should not be allowed")
+	}
+}
+
+func Test_permissionPreload(t *testing.T) {
+	db, fake := newFakeDB()
+	fake.columns = []string{"ID", "TenantID", "Body"}
+	fake.rows = [][]driver.Value{{int64(3), int64(7), "hi"}}
+
+	p := pouch.WithPolicy(SQLPouch(db), pouch.Permissions{"Note": pouch.OpRead})
+	var notes []pouch.Findable
+	err := p.Preload("Replies").Offset(0).FindEntities(&Note{}, &notes)
+	perr, ok := err.(*pouch.PermissionError)
+	if !ok || perr.Table != "Reply" || perr.Op != pouch.OpRead {
+		t.Fatal("preloading a relation the policy doesn't allow reading should be refused, was: ", err)
+	}
+	if strings.Contains(fake.last().query, "from Reply") {
+		t.Error("refused preloads should not reach the database, ran: ", fake.last().query)
+	}
+}
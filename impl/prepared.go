@@ -0,0 +1,261 @@
+package impl
+
+import (
+	"container/list"
+	"database/sql"
+	"strings"
+	"sync"
+
+	"github.com/ttacon/pouch"
+)
+
+// PrepareStatements makes the pouch prepare the statements it runs on
+// its Executor, if it can (i.e. a *sql.DB or *sql.Tx), and keep the
+// max it used last open to run again. Statements prepared on a *sql.Tx
+// are closed by it when it ends, after which the pouch forgets them.
+//
+// Only the statements the pouch generates are prepared: those that an
+// interceptor rewrote (see Intercept) run as they are, as rewriting them
+// for every operation (i.e. tagging them with the request's ID) would
+// have every one prepared, run once and evicted.
+func PrepareStatements(max int) Option {
+	return func(o *options) {
+		o.prepare = max
+	}
+}
+
+// preparer is an Executor that can prepare statements.
+type preparer interface {
+	pouch.Executor
+	Prepare(query string) (*sql.Stmt, error)
+}
+
+// lru is a least recently used cache, it is not safe for concurrent use.
+type lru struct {
+	max   int
+	order *list.List // of *lruEntry, most recently used first
+	items map[string]*list.Element
+	// evicted is called with the values that are evicted, if set
+	evicted func(value interface{})
+}
+
+type lruEntry struct {
+	key   string
+	value interface{}
+}
+
+func newLRU(max int, evicted func(value interface{})) *lru {
+	return &lru{
+		max:     max,
+		order:   list.New(),
+		items:   make(map[string]*list.Element),
+		evicted: evicted,
+	}
+}
+
+func (c *lru) get(key string) (interface{}, bool) {
+	e, ok := c.items[key]
+	if !ok {
+		return nil, false
+	}
+	c.order.MoveToFront(e)
+	return e.Value.(*lruEntry).value, true
+}
+
+func (c *lru) add(key string, value interface{}) {
+	if e, ok := c.items[key]; ok {
+		c.order.MoveToFront(e)
+		e.Value.(*lruEntry).value = value
+		return
+	}
+	c.items[key] = c.order.PushFront(&lruEntry{key: key, value: value})
+	for c.order.Len() > c.max {
+		c.remove(c.order.Back())
+	}
+}
+
+func (c *lru) remove(e *list.Element) {
+	entry := c.order.Remove(e).(*lruEntry)
+	delete(c.items, entry.key)
+	if c.evicted != nil {
+		c.evicted(entry.value)
+	}
+}
+
+func (c *lru) purge() {
+	for c.order.Len() > 0 {
+		c.remove(c.order.Back())
+	}
+}
+
+// generated memoizes the statements the pouch generates, by what they're
+// generated for (i.e. the operation, table and columns).
+var generated = struct {
+	sync.Mutex
+	*lru
+}{lru: newLRU(4096, nil)}
+
+// memoize returns the statement generated for the given key, building it
+// if it wasn't generated lately.
+func memoize(build func() string, key ...string) string {
+	k := strings.Join(key, "\x00")
+	generated.Lock()
+	query, ok := generated.get(k)
+	generated.Unlock()
+	if ok {
+		return query.(string)
+	}
+
+	query = build()
+	generated.Lock()
+	generated.add(k, query)
+	generated.Unlock()
+	return query.(string)
+}
+
+// unpreparer is an Executor which runs statements through prepared ones,
+// and can run them as they are instead.
+type unpreparer interface {
+	unprepared() pouch.Executor
+}
+
+// stmtCache is an Executor which runs statements through the ones most
+// recently prepared on the Executor it wraps.
+type stmtCache struct {
+	db preparer
+	// guards stmts, and the statements in it
+	mu sync.Mutex
+	// of *cachedStmt, by query
+	stmts *lru
+}
+
+// cachedStmt is a prepared statement, which is closed once it has been
+// evicted and no one is running it.
+type cachedStmt struct {
+	stmt    *sql.Stmt
+	users   int
+	evicted bool
+}
+
+func newStmtCache(db preparer, max int) *stmtCache {
+	return &stmtCache{
+		db: db,
+		stmts: newLRU(max, func(v interface{}) {
+			cs := v.(*cachedStmt)
+			cs.evicted = true
+			if cs.users == 0 {
+				cs.stmt.Close()
+			}
+		}),
+	}
+}
+
+// acquire returns the statement prepared for the given query, preparing
+// it if it wasn't prepared lately, it must be released once it has run.
+func (c *stmtCache) acquire(query string) (*cachedStmt, error) {
+	c.mu.Lock()
+	if v, ok := c.stmts.get(query); ok {
+		cs := v.(*cachedStmt)
+		cs.users++
+		c.mu.Unlock()
+		return cs, nil
+	}
+	c.mu.Unlock()
+
+	stmt, err := c.db.Prepare(query)
+	if err != nil {
+		return nil, c.check(err)
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if v, ok := c.stmts.get(query); ok {
+		// someone else prepared it meanwhile
+		stmt.Close()
+		cs := v.(*cachedStmt)
+		cs.users++
+		return cs, nil
+	}
+	cs := &cachedStmt{stmt: stmt, users: 1}
+	c.stmts.add(query, cs)
+	return cs, nil
+}
+
+func (c *stmtCache) release(cs *cachedStmt) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	cs.users--
+	if cs.evicted && cs.users == 0 {
+		// rows being read keep the statement open until they're closed
+		cs.stmt.Close()
+	}
+}
+
+// check forgets every statement once the transaction they were prepared
+// on has ended, and returns the given error.
+func (c *stmtCache) check(err error) error {
+	if _, inTx := c.db.(*sql.Tx); inTx && err == sql.ErrTxDone {
+		c.mu.Lock()
+		c.stmts.purge()
+		c.mu.Unlock()
+	}
+	return err
+}
+
+// errStmtClosed is the message of the error database/sql returns for
+// statements that were closed, which it doesn't export.
+const errStmtClosed = "sql: statement is closed"
+
+// run runs the given query through its prepared statement, preparing it
+// again if it was closed under the cache (i.e. by the end of the
+// transaction it was prepared on, which then fails to prepare it).
+func (c *stmtCache) run(query string, f func(stmt *sql.Stmt) error) error {
+	for retried := false; ; retried = true {
+		cs, err := c.acquire(query)
+		if err != nil {
+			return err
+		}
+		err = f(cs.stmt)
+		c.release(cs)
+		if err != nil && err.Error() == errStmtClosed && !retried {
+			c.forget(query, cs)
+			continue
+		}
+		return c.check(err)
+	}
+}
+
+// forget evicts the given statement, if it's still the one cached for
+// the query.
+func (c *stmtCache) forget(query string, cs *cachedStmt) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if e, ok := c.stmts.items[query]; ok && e.Value.(*lruEntry).value == cs {
+		c.stmts.remove(e)
+	}
+}
+
+func (c *stmtCache) Exec(query string, args ...interface{}) (res sql.Result, err error) {
+	err = c.run(query, func(stmt *sql.Stmt) error {
+		res, err = stmt.Exec(args...)
+		return err
+	})
+	return res, err
+}
+
+func (c *stmtCache) Query(query string, args ...interface{}) (rows *sql.Rows, err error) {
+	err = c.run(query, func(stmt *sql.Stmt) error {
+		rows, err = stmt.Query(args...)
+		return err
+	})
+	return rows, err
+}
+
+// QueryRow doesn't prepare the query, as a *sql.Row can't be made for a
+// statement that failed to prepare; pouches use Query instead.
+func (c *stmtCache) QueryRow(query string, args ...interface{}) *sql.Row {
+	return c.db.QueryRow(query, args...)
+}
+
+func (c *stmtCache) unprepared() pouch.Executor {
+	return c.db
+}
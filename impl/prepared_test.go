@@ -0,0 +1,110 @@
+package impl
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/ttacon/pouch"
+)
+
+func Test_preparedStatements(t *testing.T) {
+	db, fake := newFakeDB()
+	fake.columns = []string{"ID", "Name"}
+	fake.rows = [][]driver.Value{{int64(1), "ada"}}
+
+	p := SQLPouch(db, PrepareStatements(2))
+	p.Find(&Customer{ID: 1})
+	p.Find(&Customer{ID: 2})
+	if fake.prepared != 1 {
+		t.Error("the same statement should only be prepared once, was prepared: ", fake.prepared)
+	}
+
+	p.Delete(&Customer{ID: 1})
+	p.Update(&Note{ID: 1, Body: "hi"})
+	if fake.prepared != 3 || fake.closed != 1 {
+		t.Errorf("the least recently used statement should be closed, prepared %d and closed %d", fake.prepared, fake.closed)
+	}
+	p.Find(&Customer{ID: 3})
+	if fake.prepared != 4 {
+		t.Error("evicted statements should be prepared again, prepared: ", fake.prepared)
+	}
+}
+
+func Test_preparedStatementsIntercepted(t *testing.T) {
+	db, fake := newFakeDB()
+	fake.columns = []string{"ID", "Name"}
+	fake.rows = [][]driver.Value{{int64(1), "ada"}}
+
+	var request int
+	tagged := func(st *Statement, next Handler) (*Result, error) {
+		request++
+		st.Query = fmt.Sprintf("/* request %d */ %s", request, st.Query)
+		return next(st)
+	}
+	p := SQLPouch(db, PrepareStatements(2), Intercept(tagged))
+	for i := 0; i < 3; i++ {
+		if err := p.Find(&Customer{ID: 1}); err != nil {
+			t.Fatal("err should have been nil, was: ", err)
+		}
+	}
+	if stmt := fake.last(); !strings.HasPrefix(stmt.query, "/* request 3 */ select") {
+		t.Error("rewritten statements should run as they were rewritten, ran: ", stmt.query)
+	}
+	if cached := p.(*sqlPouch).db.(*stmtCache).stmts.order.Len(); cached != 0 {
+		t.Error("rewritten statements should not be prepared, had: ", cached)
+	}
+
+	p = SQLPouch(db, PrepareStatements(2), Intercept(func(st *Statement, next Handler) (*Result, error) {
+		return next(st)
+	}))
+	p.Find(&Customer{ID: 1})
+	if cached := p.(*sqlPouch).db.(*stmtCache).stmts.order.Len(); cached != 1 {
+		t.Error("intercepted statements should still be prepared, had: ", cached)
+	}
+}
+
+func Test_preparedStatementsInTx(t *testing.T) {
+	db, fake := newFakeDB()
+	fake.columns = []string{"ID", "Name"}
+	fake.rows = [][]driver.Value{{int64(1), "ada"}}
+
+	tx, err := db.Begin()
+	if err != nil {
+		t.Fatal("err should have been nil, was: ", err)
+	}
+	p := SQLPouch(tx, PrepareStatements(2))
+	if err := p.Find(&Customer{ID: 1}); err != nil {
+		t.Fatal("err should have been nil, was: ", err)
+	}
+	tx.Commit()
+
+	if err := p.Find(&Customer{ID: 1}); err != sql.ErrTxDone {
+		t.Error("statements should not outlive their transaction, was: ", err)
+	}
+	if cached := p.(*sqlPouch).db.(*stmtCache).stmts.order.Len(); cached != 0 {
+		t.Error("the statements of an ended transaction should be forgotten, had: ", cached)
+	}
+}
+
+func Test_preparedStatementsRunInTx(t *testing.T) {
+	db, fake := newFakeDB()
+	fake.affected = 1
+
+	p := SQLPouch(db, PrepareStatements(2))
+	err := p.(pouch.Transactional).RunInTx(context.Background(), func(tx pouch.Pouch) error {
+		return tx.Update(&Note{ID: 1, Body: "hi"})
+	})
+	if err != nil {
+		t.Fatal("err should have been nil, was: ", err)
+	}
+	if cached := p.(*sqlPouch).db.(*stmtCache).stmts.order.Len(); cached != 1 || fake.committed != 1 {
+		t.Errorf("transactions should run the statements the pouch prepared, had %d and committed %d", cached, fake.committed)
+	}
+	if err := p.Update(&Note{ID: 1, Body: "hi"}); err != nil {
+		t.Fatal("statements should outlive the transactions they ran in, err was: ", err)
+	}
+}
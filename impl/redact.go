@@ -0,0 +1,215 @@
+package impl
+
+import "strings"
+
+// redactedValue is logged in place of the values of sensitive columns.
+const redactedValue = "<redacted>"
+
+// redact returns the entry with the values bound to the sensitive
+// columns of its table replaced, or the entry itself if it has none.
+// Hand-written statements may read any table besides their template's,
+// so every value bound to them is replaced if any column is sensitive.
+func (s *pouchLogger) redact(e *Entry) *Entry {
+	sensitive := s.redacted[e.Table]
+	raw := e.Op == "raw" && len(s.redacted) > 0
+	if (len(sensitive) == 0 && !raw) || len(e.Args) == 0 {
+		return e
+	}
+
+	var (
+		cols = boundColumns(e.Query)
+		args = make([]interface{}, len(e.Args))
+	)
+	for i, arg := range e.Args {
+		// values whose column can't be told may be sensitive too
+		if raw || i >= len(cols) || cols[i] == "" || sensitive[strings.ToLower(cols[i])] {
+			arg = redactedValue
+		}
+		args[i] = arg
+	}
+	redacted := *e
+	redacted.Args = args
+	return &redacted
+}
+
+// token is a token of a statement, as far as telling which columns
+// values are bound to goes.
+type token struct {
+	text string
+	// whether the token is an identifier (or keyword)
+	ident bool
+	// whether the token is a string or number
+	literal bool
+}
+
+// operators that may come between a column and the value bound to it,
+// besides comparisons.
+var operators = map[string]bool{
+	"in":      true,
+	"not":     true,
+	"like":    true,
+	"rlike":   true,
+	"regexp":  true,
+	"between": true,
+	"and":     true,
+}
+
+// keywords that may come before a placeholder in place of a column.
+var keywords = map[string]bool{
+	"select": true,
+	"where":  true,
+	"set":    true,
+	"on":     true,
+	"having": true,
+	"or":     true,
+	"case":   true,
+	"when":   true,
+	"then":   true,
+	"else":   true,
+	"by":     true,
+	"limit":  true,
+	"offset": true,
+}
+
+// boundColumns returns, for every placeholder in the given statement,
+// the column its value is bound to, or "" if that can't be told. It goes
+// by the column lists of inserts, and by the column that comes before
+// the operator a placeholder is compared with otherwise, i.e. for
+// "Email = ?", "ID in (?, ?)" or "set Name = ?".
+func boundColumns(query string) []string {
+	var (
+		toks = tokenize(query)
+		cols []string
+	)
+
+	// the columns of an insert, and where its values start
+	var inserted []string
+	var values = -1
+	if len(toks) > 0 && strings.EqualFold(toks[0].text, "insert") {
+		for i, t := range toks {
+			if strings.EqualFold(t.text, "values") {
+				values = i
+				break
+			}
+		}
+		var open = false
+		for _, t := range toks[:max(values, 0)] {
+			switch {
+			case t.text == "(":
+				open = true
+			case t.text == ")":
+				open = false
+			case open && t.ident:
+				inserted = append(inserted, column(t.text))
+			}
+		}
+	}
+
+	for i, t := range toks {
+		if t.text != "?" {
+			continue
+		}
+		if values >= 0 && i > values {
+			if len(inserted) == 0 {
+				cols = append(cols, "")
+			} else {
+				cols = append(cols, inserted[len(cols)%len(inserted)])
+			}
+			continue
+		}
+
+		var col string
+		for j := i - 1; j >= 0; j-- {
+			prev := toks[j]
+			if prev.text == "?" || prev.text == "," || prev.text == "(" ||
+				operators[strings.ToLower(prev.text)] || strings.Trim(prev.text, "<>=!") == "" {
+				continue
+			}
+			if prev.ident && !keywords[strings.ToLower(prev.text)] {
+				col = column(prev.text)
+			}
+			break
+		}
+		cols = append(cols, col)
+	}
+	return cols
+}
+
+// column strips the table and quotes from a column, i.e. `u`.`Email`.
+func column(ident string) string {
+	if i := strings.LastIndex(ident, "."); i >= 0 {
+		ident = ident[i+1:]
+	}
+	return strings.Trim(ident, "`")
+}
+
+// tokenize splits a statement into identifiers, literals, placeholders,
+// operators and punctuation.
+func tokenize(query string) []token {
+	var toks []token
+	for i := 0; i < len(query); {
+		c := query[i]
+		switch {
+		case c == ' ' || c == '\t' || c == '\n' || c == '\r':
+			i++
+		case c == '\'' || c == '"':
+			// skip the literal, quotes are escaped by doubling or a backslash
+			j := i + 1
+			for j < len(query) {
+				if query[j] == '\\' {
+					j += 2
+					continue
+				}
+				if query[j] == c {
+					if j+1 < len(query) && query[j+1] == c {
+						j += 2
+						continue
+					}
+					break
+				}
+				j++
+			}
+			toks = append(toks, token{text: query[i:min(j+1, len(query))], literal: true})
+			i = j + 1
+		case isDigit(c):
+			j := i
+			for j < len(query) && (isIdent(query[j]) || query[j] == '.') {
+				j++
+			}
+			toks = append(toks, token{text: query[i:j], literal: true})
+			i = j
+		case isIdent(c) || c == '`':
+			j := i
+			for j < len(query) && (isIdent(query[j]) || query[j] == '.' || query[j] == '`') {
+				if query[j] == '`' {
+					// quoted identifiers may hold anything
+					if k := strings.IndexByte(query[j+1:], '`'); k >= 0 {
+						j += k + 1
+					}
+				}
+				j++
+			}
+			toks = append(toks, token{text: query[i:j], ident: true})
+			i = j
+		case c == '<' || c == '>' || c == '=' || c == '!':
+			j := i
+			for j < len(query) && strings.IndexByte("<>=!", query[j]) >= 0 {
+				j++
+			}
+			toks = append(toks, token{text: query[i:j]})
+			i = j
+		default:
+			toks = append(toks, token{text: string(c)})
+			i++
+		}
+	}
+	return toks
+}
+
+func isIdent(c byte) bool {
+	return c == '_' || c == '$' || 'a' <= c && c <= 'z' || 'A' <= c && c <= 'Z' || isDigit(c)
+}
+
+func isDigit(c byte) bool {
+	return '0' <= c && c <= '9'
+}
@@ -0,0 +1,161 @@
+package impl
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+
+	"github.com/go-sql-driver/mysql"
+	"github.com/ttacon/pouch"
+)
+
+// MySQL errors that running the statement, or transaction, again may not
+// run into.
+const (
+	errLockWaitTimeout = 1205
+	errDeadlock        = 1213
+)
+
+// IsTransient reports whether an error from MySQL is one that running
+// the statement, or its transaction, again may not have: a deadlock, a
+// lock wait timeout or a dropped connection, or any error that
+// pouch.IsTransient reports.
+func IsTransient(err error) bool {
+	var mysqlErr *mysql.MySQLError
+	if errors.As(err, &mysqlErr) {
+		return mysqlErr.Number == errDeadlock || mysqlErr.Number == errLockWaitTimeout
+	}
+	return errors.Is(err, mysql.ErrInvalidConn) || pouch.IsTransient(err)
+}
+
+// Retry makes the pouch retry the statements that fail with a transient
+// error (see IsTransient, unless the policy says otherwise) as the policy
+// says. Statements that create entities or update Versioned ones are
+// only retried if the policy allows it, and statements in a transaction
+// (RunInTx's, or those of a pouch made with a *sql.Tx) are never retried
+// on their own, as MySQL rolls back transactions that deadlock: RunInTx
+// retries the whole transaction instead. A pouch that
+// retries should not also be wrapped by pouch.Retrying, which would
+// multiply the attempts.
+func Retry(policy pouch.RetryPolicy) Option {
+	return func(o *options) {
+		if policy.Retryable == nil {
+			policy.Retryable = IsTransient
+		}
+		o.retry = &policy
+	}
+}
+
+// retryingExecutor retries the statements of an operation.
+type retryingExecutor struct {
+	db         pouch.Executor
+	policy     *pouch.RetryPolicy
+	idempotent bool
+	ctx        context.Context
+}
+
+func (e *retryingExecutor) Exec(query string, args ...interface{}) (res sql.Result, err error) {
+	err = e.policy.Do(e.ctx, e.idempotent, func() error {
+		res, err = e.db.Exec(query, args...)
+		return err
+	})
+	return res, err
+}
+
+func (e *retryingExecutor) Query(query string, args ...interface{}) (rows *sql.Rows, err error) {
+	err = e.policy.Do(e.ctx, e.idempotent, func() error {
+		rows, err = e.db.Query(query, args...)
+		return err
+	})
+	return rows, err
+}
+
+// QueryRow isn't retried, as its error only surfaces once it's scanned;
+// pouches use Query instead.
+func (e *retryingExecutor) QueryRow(query string, args ...interface{}) *sql.Row {
+	return e.db.QueryRow(query, args...)
+}
+
+// beginner is an Executor which can begin transactions, i.e. a *sql.DB.
+type beginner interface {
+	BeginTx(ctx context.Context, opts *sql.TxOptions) (*sql.Tx, error)
+}
+
+// RunInTx runs fn with a pouch whose operations all run in a transaction
+// begun on the pouch's Executor, which must be a *sql.DB. The transaction
+// is committed if fn returns nil and rolled back otherwise. If the pouch
+// retries (see Retry), the whole transaction is run again when it fails
+// with a transient error, so fn should have no effects outside of it.
+func (s *sqlPouch) RunInTx(ctx context.Context, fn func(p pouch.Pouch) error) error {
+	return s.query().RunInTx(ctx, fn)
+}
+
+// RunInTx runs fn as the pouch's RunInTx does, so that pouches wrapping
+// the query (i.e. pouch.TenantPouch) can run transactions through it.
+func (s *sqlQuery) RunInTx(ctx context.Context, fn func(p pouch.Pouch) error) error {
+	var db = s.db
+	stmts, prepared := s.db.(*stmtCache)
+	if prepared {
+		db = stmts.db
+	}
+	b, ok := db.(beginner)
+	if !ok {
+		return errors.New("the pouch's Executor cannot begin transactions")
+	}
+
+	run := func() error {
+		tx, err := b.BeginTx(ctx, nil)
+		if err != nil {
+			return err
+		}
+		var exec pouch.Executor = tx
+		if prepared {
+			exec = &txStmts{tx: tx, c: stmts}
+		}
+		// statements in the transaction aren't retried on their own
+		var opts = s.opts
+		opts.retry = nil
+		if err := fn(&sqlPouch{db: exec, l: s.l, opts: opts}); err != nil {
+			tx.Rollback()
+			return err
+		}
+		return tx.Commit()
+	}
+	if s.opts.retry == nil {
+		return run()
+	}
+	return s.opts.retry.Do(ctx, true, run)
+}
+
+// txStmts runs the statements of a transaction through the statements a
+// cache prepared on the transaction's *sql.DB, the transaction's copies
+// of which it closes when it ends, so nothing prepared for it outlives
+// it.
+type txStmts struct {
+	tx *sql.Tx
+	c  *stmtCache
+}
+
+func (t *txStmts) Exec(query string, args ...interface{}) (res sql.Result, err error) {
+	err = t.c.run(query, func(stmt *sql.Stmt) error {
+		res, err = t.tx.Stmt(stmt).Exec(args...)
+		return err
+	})
+	return res, err
+}
+
+func (t *txStmts) Query(query string, args ...interface{}) (rows *sql.Rows, err error) {
+	err = t.c.run(query, func(stmt *sql.Stmt) error {
+		rows, err = t.tx.Stmt(stmt).Query(args...)
+		return err
+	})
+	return rows, err
+}
+
+func (t *txStmts) QueryRow(query string, args ...interface{}) *sql.Row {
+	return t.tx.QueryRow(query, args...)
+}
+
+func (t *txStmts) unprepared() pouch.Executor {
+	return t.tx
+}
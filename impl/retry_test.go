@@ -0,0 +1,202 @@
+package impl
+
+import (
+	"context"
+	"database/sql/driver"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-sql-driver/mysql"
+	"github.com/ttacon/pouch"
+)
+
+var errDeadlocked = &mysql.MySQLError{Number: 1213, Message: "Deadlock found when trying to get lock"}
+
+// failing returns an interceptor failing the first n statements with the
+// given error, and how many statements it was given.
+func failing(n int, err error) (Interceptor, *int) {
+	var ran int
+	return func(st *Statement, next Handler) (*Result, error) {
+		ran++
+		if ran <= n {
+			return nil, err
+		}
+		return next(st)
+	}, &ran
+}
+
+var quickRetries = pouch.RetryPolicy{MaxAttempts: 3, BaseDelay: time.Millisecond}
+
+func Test_isTransient(t *testing.T) {
+	for err, transient := range map[error]bool{
+		errDeadlocked:                   true,
+		&mysql.MySQLError{Number: 1205}: true,
+		&mysql.MySQLError{Number: 1062}: false,
+		mysql.ErrInvalidConn:            true,
+		driver.ErrBadConn:               true,
+		context.DeadlineExceeded:        false,
+		errors.New("syntax error"):      false,
+	} {
+		if IsTransient(err) != transient {
+			t.Errorf("%v should have been transient: %v", err, transient)
+		}
+	}
+}
+
+func Test_retry(t *testing.T) {
+	db, fake := newFakeDB()
+	fake.columns = []string{"ID", "Name"}
+	fake.rows = [][]driver.Value{{int64(1), "ada"}}
+	fake.affected = 1
+
+	deadlocks, ran := failing(2, errDeadlocked)
+	p := SQLPouch(db, Intercept(deadlocks), Retry(quickRetries))
+	if err := p.Find(&Customer{ID: 1}); err != nil {
+		t.Fatal("transient errors should be retried, err was: ", err)
+	}
+	if *ran != 3 {
+		t.Error("the statement should have run three times, ran: ", *ran)
+	}
+
+	deadlocks, ran = failing(3, errDeadlocked)
+	p = SQLPouch(db, Intercept(deadlocks), Retry(quickRetries))
+	if err := p.Update(&Note{ID: 1, Body: "hi"}); err != errDeadlocked {
+		t.Error("the last error should be returned once attempts run out, was: ", err)
+	}
+
+	deadlocks, ran = failing(1, errDeadlocked)
+	p = SQLPouch(db, Intercept(deadlocks), Retry(quickRetries))
+	if err := p.Create(&Note{Body: "hi"}); err != errDeadlocked || *ran != 1 {
+		t.Errorf("creates should not be retried, ran %d times with: %v", *ran, err)
+	}
+
+	policy := quickRetries
+	policy.RetryCreates = true
+	deadlocks, ran = failing(1, errDeadlocked)
+	p = SQLPouch(db, Intercept(deadlocks), Retry(policy))
+	if err := p.Create(&Note{Body: "hi"}); err != nil || *ran != 2 {
+		t.Errorf("creates should be retried when allowed, ran %d times with: %v", *ran, err)
+	}
+
+	deadlocks, ran = failing(1, errDeadlocked)
+	p = SQLPouch(db, Intercept(deadlocks), Retry(quickRetries))
+	if err := p.Update(&Plant{ID: 1, Name: "fern"}); err != errDeadlocked || *ran != 1 {
+		t.Errorf("updates of versioned entities should not be retried, ran %d times with: %v", *ran, err)
+	}
+
+	tx, err := db.Begin()
+	if err != nil {
+		t.Fatal("err should have been nil, was: ", err)
+	}
+	deadlocks, ran = failing(1, errDeadlocked)
+	p = SQLPouch(tx, Intercept(deadlocks), Retry(quickRetries))
+	if err := p.Delete(&Customer{ID: 1}); err != errDeadlocked || *ran != 1 {
+		t.Errorf("statements in a transaction should not be retried, ran %d times with: %v", *ran, err)
+	}
+	tx.Rollback()
+
+	duplicate := &mysql.MySQLError{Number: 1062}
+	failed, ran := failing(1, duplicate)
+	p = SQLPouch(db, Intercept(failed), Retry(quickRetries))
+	if err := p.Delete(&Customer{ID: 1}); err != duplicate || *ran != 1 {
+		t.Errorf("errors that aren't transient should not be retried, ran %d times with: %v", *ran, err)
+	}
+}
+
+func Test_runInTx(t *testing.T) {
+	db, fake := newFakeDB()
+	fake.affected = 1
+
+	deadlocks, ran := failing(1, errDeadlocked)
+	p := SQLPouch(db, Intercept(deadlocks), Retry(quickRetries))
+	var runs int
+	err := p.(pouch.Transactional).RunInTx(context.Background(), func(tx pouch.Pouch) error {
+		runs++
+		if err := tx.Update(&Note{ID: 1, Body: "hi"}); err != nil {
+			return err
+		}
+		return tx.Delete(&Customer{ID: 2})
+	})
+	if err != nil {
+		t.Fatal("err should have been nil, was: ", err)
+	}
+	if runs != 2 || *ran != 3 {
+		t.Errorf("the whole transaction should have been retried, ran it %d times and %d statements", runs, *ran)
+	}
+	if fake.rolledBack != 1 || fake.committed != 1 {
+		t.Errorf("the failed transaction should be rolled back, rolled back %d and committed %d", fake.rolledBack, fake.committed)
+	}
+
+	errRefused := errors.New("refused")
+	err = p.(pouch.Transactional).RunInTx(context.Background(), func(tx pouch.Pouch) error {
+		return errRefused
+	})
+	if err != errRefused || fake.rolledBack != 2 {
+		t.Error("the transaction should be rolled back when its func fails, err was: ", err)
+	}
+}
+
+func Test_runInTxDecorated(t *testing.T) {
+	db, fake := newFakeDB()
+	fake.columns = []string{"ID", "TenantID", "Body"}
+	fake.rows = [][]driver.Value{{int64(3), int64(7), "hi"}}
+
+	deadlocks, ran := failing(1, errDeadlocked)
+	policy := quickRetries
+	policy.Retryable = IsTransient
+	p := pouch.Retrying(pouch.TenantPouch(SQLPouch(db, Intercept(deadlocks)), "TenantID", 7), policy)
+	var runs int
+	err := p.(pouch.Transactional).RunInTx(context.Background(), func(tx pouch.Pouch) error {
+		runs++
+		return tx.Find(&Note{ID: 3})
+	})
+	if err != nil {
+		t.Fatal("err should have been nil, was: ", err)
+	}
+	if runs != 2 || *ran != 2 {
+		t.Errorf("the whole transaction should have been retried, ran it %d times and %d statements", runs, *ran)
+	}
+	if stmt := fake.last(); !strings.Contains(stmt.query, "where TenantID = ? AND ID = ?") {
+		t.Error("the transaction's pouch should be decorated as the pouch is, ran: ", stmt.query)
+	}
+	if fake.committed != 1 {
+		t.Error("the transaction should have been committed, committed: ", fake.committed)
+	}
+}
+
+func Test_retrying(t *testing.T) {
+	db, fake := newFakeDB()
+	fake.columns = []string{"ID", "Name"}
+	fake.rows = [][]driver.Value{{int64(1), "ada"}, {int64(2), "grace"}}
+
+	deadlocks, ran := failing(1, errDeadlocked)
+	policy := quickRetries
+	policy.Retryable = IsTransient
+	p := pouch.Retrying(SQLPouch(db, Intercept(deadlocks)), policy)
+
+	var res []pouch.Findable
+	if err := p.Where("Name != ?", "").FindEntities(&Customer{}, &res); err != nil {
+		t.Fatal("err should have been nil, was: ", err)
+	}
+	if *ran != 2 || len(res) != 2 {
+		t.Errorf("the query should have been retried, ran %d times and found %d", *ran, len(res))
+	}
+
+	deadlocks, ran = failing(1, errDeadlocked)
+	p = pouch.Retrying(SQLPouch(db, Intercept(deadlocks)), policy)
+	if err := p.Create(&Note{Body: "hi"}); err != errDeadlocked || *ran != 1 {
+		t.Errorf("creates should not be retried, ran %d times with: %v", *ran, err)
+	}
+
+	deadlocks, ran = failing(1, errDeadlocked)
+	p = pouch.Retrying(SQLPouch(db, Intercept(deadlocks)), policy)
+	if err := p.UpdateColumns(&Plant{ID: 1, Name: "fern"}, "Name"); err != errDeadlocked || *ran != 1 {
+		t.Errorf("updates of versioned entities should not be retried, ran %d times with: %v", *ran, err)
+	}
+
+	if _, ok := p.(pouch.Transactional); !ok {
+		t.Error("the retrying pouch should run transactions")
+	}
+}
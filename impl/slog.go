@@ -0,0 +1,80 @@
+package impl
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+)
+
+// SlogLogger adapts a log/slog Logger to a Logger, its handler decides
+// which levels are logged. Statements are logged at debug level, with
+// their Entry's fields as attributes, the values bound to them only
+// if the handler logs TRACE (debug-4) too; slow statements are logged
+// at warn level. Print logs at info level, or at the level it was given
+// with V.
+func SlogLogger(l *slog.Logger) Logger {
+	return &slogLogger{l: l, level: slog.LevelInfo}
+}
+
+type slogLogger struct {
+	l     *slog.Logger
+	level slog.Level
+}
+
+func (s *slogLogger) Print(v ...interface{}) {
+	s.l.Log(context.Background(), s.level, fmt.Sprint(v...))
+}
+
+func (s *slogLogger) Log(e *Entry) {
+	var ctx, level = context.Background(), slog.LevelDebug
+	if e.Slow {
+		level = slog.LevelWarn
+	}
+	if !s.l.Enabled(ctx, level) {
+		return
+	}
+	var attrs = []slog.Attr{
+		slog.String("op", e.Op),
+		slog.String("table", e.Table),
+		slog.String("query", e.Query),
+		slog.Duration("duration", e.Duration),
+		slog.Int64("rows", e.Rows),
+	}
+	if s.l.Enabled(ctx, slogLevel(TRACE)) {
+		attrs = append(attrs, slog.Any("args", e.Args))
+	}
+	if e.Err != nil {
+		attrs = append(attrs, slog.Any("error", e.Err))
+	}
+	if e.Slow {
+		attrs = append(attrs, slog.Bool("slow", true), slog.String("fingerprint", e.Fingerprint))
+	}
+	if e.Plan != "" {
+		attrs = append(attrs, slog.String("plan", e.Plan))
+	}
+	if e.PlanErr != nil {
+		attrs = append(attrs, slog.Any("plan_error", e.PlanErr))
+	}
+	s.l.LogAttrs(ctx, level, "pouch "+e.Op, attrs...)
+}
+
+func (s *slogLogger) V(level VerbosityLevel) Logger {
+	return &slogLogger{l: s.l, level: slogLevel(level)}
+}
+
+// SetVerbosity does nothing, the handler decides which levels are logged.
+func (s *slogLogger) SetVerbosity(level VerbosityLevel) {}
+
+func slogLevel(level VerbosityLevel) slog.Level {
+	switch level {
+	case TRACE:
+		return slog.LevelDebug - 4
+	case DEBUG:
+		return slog.LevelDebug
+	case WARN:
+		return slog.LevelWarn
+	case ERROR:
+		return slog.LevelError
+	}
+	return slog.LevelInfo
+}
@@ -0,0 +1,157 @@
+package impl
+
+import (
+	"database/sql"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ttacon/pouch"
+)
+
+// slowQueries spots the statements of a pouch that take longer than a
+// threshold, see SlowQueryThreshold.
+type slowQueries struct {
+	threshold time.Duration
+	// where, and at most how often, slow statements are explained
+	explain pouch.Executor
+	every   time.Duration
+	notify  func(e *Entry)
+
+	mu        sync.Mutex
+	explained time.Time
+}
+
+// check returns the entry marked as slow, with its fingerprint, if it
+// took longer than the threshold, or the entry itself otherwise.
+func (s *slowQueries) check(e *Entry) *Entry {
+	if s.threshold <= 0 || e.Duration < s.threshold {
+		return e
+	}
+	slow := *e
+	slow.Slow = true
+	slow.Fingerprint = Fingerprint(e.Query)
+	return &slow
+}
+
+// report hands the entry of a slow statement to notify, once it was
+// logged. Statements that are explained are explained in the background,
+// with the values in the unredacted entry, and handed over with their
+// plan once that's done.
+func (s *slowQueries) report(e, unredacted *Entry) {
+	if s.notify == nil {
+		return
+	}
+	if s.explain == nil || !explainable(e.Query) || !s.allow() {
+		s.notify(e)
+		return
+	}
+
+	var (
+		slow  = *e
+		query = unredacted.Query
+		args  = append([]interface{}(nil), unredacted.Args...)
+	)
+	go func() {
+		slow.Plan, slow.PlanErr = explain(s.explain, query, args)
+		s.notify(&slow)
+	}()
+}
+
+// allow reports whether a statement may be explained now, going by when
+// the last one was.
+func (s *slowQueries) allow() bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if !s.explained.IsZero() && time.Since(s.explained) < s.every {
+		return false
+	}
+	s.explained = time.Now()
+	return true
+}
+
+// explainable reports whether MySQL can explain the given statement.
+func explainable(query string) bool {
+	toks := tokenize(query)
+	if len(toks) == 0 {
+		return false
+	}
+	switch strings.ToLower(toks[0].text) {
+	case "select", "insert", "update", "delete", "replace":
+		return true
+	}
+	return false
+}
+
+// explain runs EXPLAIN on the given statement, and returns the plan as
+// tab separated rows under a header of its columns.
+func explain(db pouch.Executor, query string, args []interface{}) (string, error) {
+	rows, err := db.Query("explain "+query, args...)
+	if err != nil {
+		return "", err
+	}
+	defer rows.Close()
+
+	cols, err := rows.Columns()
+	if err != nil {
+		return "", err
+	}
+	var plan = []string{strings.Join(cols, "\t")}
+	for rows.Next() {
+		var (
+			vals   = make([]sql.NullString, len(cols))
+			fields = make([]interface{}, len(cols))
+		)
+		for i := range vals {
+			fields[i] = &vals[i]
+		}
+		if err := rows.Scan(fields...); err != nil {
+			return "", err
+		}
+		var row = make([]string, len(cols))
+		for i, val := range vals {
+			row[i] = "NULL"
+			if val.Valid {
+				row[i] = val.String
+			}
+		}
+		plan = append(plan, strings.Join(row, "\t"))
+	}
+	return strings.Join(plan, "\n"), rows.Err()
+}
+
+// Fingerprint normalizes a statement so that statements which only
+// differ by their values (or how many there are in a list), case or
+// spacing have the same one, i.e.
+//
+//	select ID from User where Name = 'ada' and Age in (1, 2)
+//
+// becomes "select id from user where name = ? and age in (?+)".
+func Fingerprint(query string) string {
+	var b strings.Builder
+	toks := tokenize(query)
+	for i := 0; i < len(toks); i++ {
+		t := toks[i]
+		text := strings.ToLower(t.text)
+		if t.literal {
+			text = "?"
+		}
+
+		// collapse lists of values
+		if text == "(" {
+			j := i + 1
+			for j+1 < len(toks) && (toks[j].text == "?" || toks[j].literal) && toks[j+1].text == "," {
+				j += 2
+			}
+			if j > i+1 && j+1 < len(toks) && (toks[j].text == "?" || toks[j].literal) && toks[j+1].text == ")" {
+				text, i = "(?+)", j+1
+			}
+		}
+
+		if b.Len() > 0 && text != "," && text != ")" && !strings.HasSuffix(b.String(), "(") {
+			b.WriteByte(' ')
+		}
+		b.WriteString(text)
+	}
+	return b.String()
+}
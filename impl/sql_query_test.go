@@ -0,0 +1,754 @@
+package impl
+
+import (
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/ttacon/pouch"
+)
+
+func Test_softDelete(t *testing.T) {
+	db, fake := newFakeDB()
+	p := SQLPouch(db)
+
+	deletedAt := time.Date(2015, 3, 14, 0, 0, 0, 0, time.UTC)
+	now = func() time.Time { return deletedAt }
+	defer func() { now = time.Now }()
+
+	var plant = Plant{ID: 3}
+	if err := p.Delete(&plant); err != nil {
+		t.Fatal("err should have been nil, was: ", err)
+	}
+
+	stmt := fake.last()
+	if stmt.query != "update Plant\nset DeletedAt = ?\nwhere ID = ? AND DeletedAt is null" {
+		t.Error("delete should have been an update of a plant that isn't deleted yet, was: ", stmt.query)
+	}
+	if len(stmt.args) != 2 || stmt.args[0] != deletedAt || stmt.args[1] != int64(3) {
+		t.Error("unexpected values for soft delete: ", stmt.args)
+	}
+
+	if err := p.OnlyDeleted().HardDelete(&plant); err != nil {
+		t.Fatal("err should have been nil, was: ", err)
+	}
+	if stmt := fake.last(); !strings.HasPrefix(stmt.query, "delete\nfrom Plant\nwhere ID = ?") {
+		t.Error("hard delete should have been a delete, was: ", stmt.query)
+	}
+
+	if err := p.OnlyDeleted().Restore(&plant); err != nil {
+		t.Fatal("err should have been nil, was: ", err)
+	}
+	stmt = fake.last()
+	if len(stmt.args) != 2 || stmt.args[0] != nil {
+		t.Error("restoring should clear the deleted at column, was: ", stmt.args)
+	}
+	if stmt.query != "update Plant\nset DeletedAt = ?\nwhere ID = ?" {
+		t.Error("restoring should not require the plant to be undeleted, was: ", stmt.query)
+	}
+}
+
+func Test_softDeleteReads(t *testing.T) {
+	db, fake := newFakeDB()
+	p := SQLPouch(db)
+	fake.columns = []string{"ID", "Name", "DeletedAt"}
+	fake.rows = [][]driver.Value{{int64(1), "fern", nil}}
+
+	tests := []struct {
+		q    pouch.Query
+		want string
+	}{
+		{p.Where("Name = ?", "fern"), "where Name = ? AND DeletedAt is null"},
+		{p.WithDeleted().Where("Name = ?", "fern"), "where Name = ?\n"},
+		{p.OnlyDeleted().Where("Name = ?", "fern"), "where Name = ? AND DeletedAt is not null"},
+	}
+
+	for _, test := range tests {
+		var plants []pouch.Findable
+		if err := test.q.FindEntities(&Plant{}, &plants); err != nil {
+			t.Fatal("err should have been nil, was: ", err)
+		}
+		if stmt := fake.last(); !strings.Contains(stmt.query, test.want) {
+			t.Errorf("expected query to contain %q, was: %q", test.want, stmt.query)
+		}
+		if len(plants) != 1 {
+			t.Error("expected to find 1 plant, found: ", len(plants))
+		}
+	}
+
+	var plant = Plant{ID: 1}
+	if err := p.Find(&plant); err != nil {
+		t.Fatal("err should have been nil, was: ", err)
+	}
+	if stmt := fake.last(); !strings.Contains(stmt.query, "where ID = ? AND DeletedAt is null") {
+		t.Error("find should have hidden soft deleted plants, was: ", stmt.query)
+	}
+	if plant.Name != "fern" {
+		t.Error("name should have been 'fern', was: ", plant.Name)
+	}
+}
+
+func Test_versionedUpdate(t *testing.T) {
+	db, fake := newFakeDB()
+	p := SQLPouch(db)
+
+	var plant = Plant{ID: 4, Name: "cactus", Revision: 7}
+	if err := p.Update(&plant); err != nil {
+		t.Fatal("err should have been nil, was: ", err)
+	}
+
+	stmt := fake.last()
+	if stmt.query != "update Plant\nset Name = ?, Revision = ?\nwhere ID = ? AND Revision = ?" {
+		t.Error("unexpected versioned update: ", stmt.query)
+	}
+	if len(stmt.args) != 4 || stmt.args[1] != int64(8) || stmt.args[3] != int64(7) {
+		t.Error("unexpected values for versioned update: ", stmt.args)
+	}
+	if plant.Revision != 8 {
+		t.Error("revision should have been bumped to 8, was: ", plant.Revision)
+	}
+
+	fake.affected = 0
+	err := p.Update(&plant)
+	if _, ok := err.(*pouch.ConflictError); !ok {
+		t.Fatal("expected a conflict error, was: ", err)
+	}
+	if plant.Revision != 8 {
+		t.Error("revision should not have changed on conflict, was: ", plant.Revision)
+	}
+
+	var other = Plant{ID: 5, Name: "aloe", Revision: 1}
+	err = p.UpdateAll([]pouch.Updateable{&plant, &other})
+	cErr, ok := err.(*pouch.ConflictError)
+	if !ok {
+		t.Fatal("expected a conflict error, was: ", err)
+	}
+	if len(cErr.Conflicts) != 2 || cErr.Conflicts[1] != &other {
+		t.Error("both plants should have conflicted, was: ", cErr.Conflicts)
+	}
+}
+
+func Test_strictMode(t *testing.T) {
+	db, fake := newFakeDB()
+	fake.affected = 0
+
+	var food = Food{ID: 42}
+	if err := SQLPouch(db).Delete(&food); err != nil {
+		t.Error("a lenient pouch should ignore missing entities, was: ", err)
+	}
+
+	p := SQLPouch(db, Strict())
+	err := p.Delete(&food)
+	if nErr, ok := err.(*pouch.NotFoundError); !ok || nErr.Missing[0] != &food {
+		t.Error("expected a not found error, was: ", err)
+	}
+
+	var other = Food{ID: 43}
+	err = p.UpdateAll([]pouch.Updateable{&food, &other})
+	if nErr, ok := err.(*pouch.NotFoundError); !ok || len(nErr.Missing) != 2 {
+		t.Error("expected both foods to be missing, was: ", err)
+	}
+	if n := len(fake.all()); n != 4 {
+		t.Error("every entity should have been attempted, statements run: ", n)
+	}
+
+	fake.affected = 1
+	if err := p.Delete(&food); err != nil {
+		t.Error("err should have been nil, was: ", err)
+	}
+}
+
+func Test_partialUpdates(t *testing.T) {
+	db, fake := newFakeDB()
+	p := SQLPouch(db)
+	fake.columns = []string{"ID", "Name", "Color"}
+	fake.rows = [][]driver.Value{{int64(9), "tulip", "red"}}
+
+	var flower = Flower{ID: 9}
+	if err := p.Find(&flower); err != nil {
+		t.Fatal("err should have been nil, was: ", err)
+	}
+	before := len(fake.all())
+
+	if err := p.Update(&flower); err != nil {
+		t.Fatal("err should have been nil, was: ", err)
+	}
+	if len(fake.all()) != before {
+		t.Error("updating an unchanged entity shouldn't run anything, ran: ", fake.last().query)
+	}
+
+	flower.Color = "yellow"
+	if err := p.Update(&flower); err != nil {
+		t.Fatal("err should have been nil, was: ", err)
+	}
+	stmt := fake.last()
+	if stmt.query != "update Flower\nset Color = ?\nwhere ID = ?" {
+		t.Error("only the changed column should have been updated, was: ", stmt.query)
+	}
+
+	flower.Name, flower.Color = "daffodil", "white"
+	if err := p.UpdateColumns(&flower, "Name"); err != nil {
+		t.Fatal("err should have been nil, was: ", err)
+	}
+	stmt = fake.last()
+	if stmt.query != "update Flower\nset Name = ?\nwhere ID = ?" || stmt.args[0] != "daffodil" {
+		t.Error("only the named column should have been updated, was: ", stmt.query, stmt.args)
+	}
+
+	if err := p.Update(&flower); err != nil {
+		t.Fatal("err should have been nil, was: ", err)
+	}
+	if stmt = fake.last(); stmt.query != "update Flower\nset Color = ?\nwhere ID = ?" {
+		t.Error("the color should still have been left to update, was: ", stmt.query)
+	}
+
+	// strict updates must still find unchanged entities
+	strict := SQLPouch(db, Strict())
+	fake.columns, fake.rows = []string{"1"}, [][]driver.Value{{int64(1)}}
+	if err := strict.Update(&flower); err != nil {
+		t.Fatal("err should have been nil, was: ", err)
+	}
+	if stmt = fake.last(); stmt.query != "select 1\nfrom Flower\nwhere ID = ?\nlimit 1" {
+		t.Error("a strict update of an unchanged entity should look for it, ran: ", stmt.query)
+	}
+	fake.rows = nil
+	var notFound *pouch.NotFoundError
+	if err := strict.Update(&flower); !errors.As(err, &notFound) {
+		t.Error("a strict update of a missing, unchanged entity should fail, was: ", err)
+	}
+}
+
+// Flower is a tracked entity.
+type Flower struct {
+	pouch.Tracker
+	ID    int
+	Name  string
+	Color string
+}
+
+func (f *Flower) IdentifiableFields() ([]string, []interface{}) {
+	return []string{"ID"}, []interface{}{f.ID}
+}
+
+func (f *Flower) GetFieldsFor(cols []string) []interface{} {
+	_, fields := f.GetAllFields()
+	return fields
+}
+
+func (f *Flower) GetAllFields() ([]string, []interface{}) {
+	return []string{"ID", "Name", "Color"}, []interface{}{&f.ID, &f.Name, &f.Color}
+}
+
+func (f *Flower) FieldsFor(cols []string) []interface{} {
+	var vals = make([]interface{}, len(cols))
+	for i, col := range cols {
+		switch col {
+		case "Name":
+			vals[i] = &f.Name
+		case "Color":
+			vals[i] = &f.Color
+		}
+	}
+	return vals
+}
+
+func (f *Flower) InsertableFields() ([]string, []interface{}) {
+	return []string{"Name", "Color"}, []interface{}{f.Name, f.Color}
+}
+
+func (f *Flower) SetIdentifier(i interface{}) error { return nil }
+func (f *Flower) Table() string                     { return "Flower" }
+func (f *Flower) FindableCopy() pouch.Findable      { return &Flower{} }
+
+func Test_bulkWrites(t *testing.T) {
+	db, fake := newFakeDB()
+	p := SQLPouch(db)
+	fake.affected = 12
+
+	if _, err := p.Offset(0).DeleteWhere(&Food{}); err != pouch.ErrUnconstrained {
+		t.Error("expected an unconstrained delete to be refused, was: ", err)
+	}
+	if len(fake.all()) != 0 {
+		t.Error("nothing should have been run, ran: ", fake.last().query)
+	}
+
+	n, err := p.Where("Name = ?", "kale").UpdateWhere(&Food{}, map[string]interface{}{
+		"NullableField": "stale",
+		"Name":          "old kale",
+	})
+	if err != nil || n != 12 {
+		t.Fatal("expected 12 foods to be updated, was: ", n, err)
+	}
+	stmt := fake.last()
+	if stmt.query != "update Food\nset Name = ?, NullableField = ?\nwhere Name = ?\n" {
+		t.Error("unexpected bulk update: ", stmt.query)
+	}
+	if len(stmt.args) != 3 || stmt.args[0] != "old kale" || stmt.args[2] != "kale" {
+		t.Error("unexpected values for bulk update: ", stmt.args)
+	}
+
+	if _, err := p.Offset(0).AllowUnconstrained().DeleteWhere(&Food{}); err != nil {
+		t.Fatal("err should have been nil, was: ", err)
+	}
+	if stmt = fake.last(); stmt.query != "delete\nfrom Food\n" {
+		t.Error("unexpected unconstrained delete: ", stmt.query)
+	}
+
+	if _, err := p.Where("Name = ?", "fern").DeleteWhere(&Plant{}); err != nil {
+		t.Fatal("err should have been nil, was: ", err)
+	}
+	stmt = fake.last()
+	if stmt.query != "update Plant\nset DeletedAt = ?\nwhere Name = ? AND DeletedAt is null\n" {
+		t.Error("plants should have been soft deleted, was: ", stmt.query)
+	}
+}
+
+func Test_preload(t *testing.T) {
+	db, fake := newFakeDB()
+	p := SQLPouch(db)
+	fake.respond = func(query string, args []driver.Value) ([]string, [][]driver.Value) {
+		switch {
+		case strings.Contains(query, "from Orders"):
+			return []string{"ID", "CustomerID"}, [][]driver.Value{
+				{int64(1), int64(10)}, {int64(2), int64(11)}, {int64(3), int64(10)},
+			}
+		case strings.Contains(query, "from Customer"):
+			return []string{"ID", "Name"}, [][]driver.Value{
+				{int64(10), "ada"}, {int64(11), "grace"},
+			}
+		case strings.Contains(query, "from Item"):
+			return []string{"ID", "OrderID"}, [][]driver.Value{
+				{int64(100), int64(1)}, {int64(101), int64(1)}, {int64(102), int64(3)},
+			}
+		}
+		return nil, nil
+	}
+
+	var orders []pouch.Findable
+	err := p.Preload("Customer", "Items").FindEntities(&Order{}, &orders)
+	if err != nil {
+		t.Fatal("err should have been nil, was: ", err)
+	}
+	if len(orders) != 3 {
+		t.Fatal("expected 3 orders, found: ", len(orders))
+	}
+
+	stmts := fake.all()
+	if len(stmts) != 3 {
+		t.Fatal("expected one query per relation, ran: ", len(stmts))
+	}
+	if !strings.Contains(stmts[1].query, "where ID in (?, ?)") || len(stmts[1].args) != 2 {
+		t.Error("customers should have been found in one batch, was: ", stmts[1].query, stmts[1].args)
+	}
+	if !strings.Contains(stmts[2].query, "where OrderID in (?, ?, ?)") {
+		t.Error("items should have been found in one batch, was: ", stmts[2].query)
+	}
+
+	var names, items []string
+	for _, o := range orders {
+		order := o.(*Order)
+		names = append(names, order.Customer.Name)
+		items = append(items, fmt.Sprint(len(order.Items)))
+	}
+	if strings.Join(names, ",") != "ada,grace,ada" {
+		t.Error("customers were attached to the wrong orders: ", names)
+	}
+	if strings.Join(items, ",") != "2,0,1" {
+		t.Error("items were attached to the wrong orders: ", items)
+	}
+}
+
+// Order is related to a customer and items.
+func Test_keysetPagination(t *testing.T) {
+	db, fake := newFakeDB()
+	p := SQLPouch(db)
+	fake.columns = []string{"ID", "Name"}
+	fake.rows = [][]driver.Value{{int64(4), "ada"}, {int64(7), "grace"}}
+
+	var page []pouch.Findable
+	next, prev, err := p.OrderBy("Name").Limit(2).FindPage(&Customer{}, &page)
+	if err != nil {
+		t.Fatal("err should have been nil, was: ", err)
+	}
+	if stmt := fake.last(); !strings.HasSuffix(stmt.query, "from Customer\norder by Name, ID\nlimit 2") {
+		t.Error("the identity should break ties in the order, was: ", stmt.query)
+	}
+	if len(next) == 0 || len(prev) != 0 {
+		t.Fatal("the first page should only have a next cursor, had: ", next, prev)
+	}
+
+	page = nil
+	if _, _, err = p.OrderBy("Name").Limit(2).After(next).FindPage(&Customer{}, &page); err != nil {
+		t.Fatal("err should have been nil, was: ", err)
+	}
+	stmt := fake.last()
+	if !strings.Contains(stmt.query, "where (Name, ID) > (?, ?)\norder by Name, ID\n") {
+		t.Error("expected a row-value comparison, was: ", stmt.query)
+	}
+	if len(stmt.args) != 2 || stmt.args[0] != "grace" || stmt.args[1] != int64(7) {
+		t.Error("the cursor should hold the last entity's values, was: ", stmt.args)
+	}
+
+	page = nil
+	fake.rows = [][]driver.Value{{int64(7), "grace"}, {int64(4), "ada"}}
+	if _, _, err = p.OrderBy("Name desc").Limit(2).Before(next).FindPage(&Customer{}, &page); err != nil {
+		t.Fatal("err should have been nil, was: ", err)
+	}
+	stmt = fake.last()
+	if !strings.Contains(stmt.query, "where ((Name > ?) OR (Name = ? AND ID < ?))\norder by Name, ID desc\n") {
+		t.Error("expected a reversed comparison for mixed directions, was: ", stmt.query)
+	}
+	if page[0].(*Customer).Name != "ada" {
+		t.Error("paging backward should keep the query's order, was: ", page[0].(*Customer).Name)
+	}
+
+	if err = p.After("not a cursor").FindEntities(&Customer{}, &page); err == nil {
+		t.Error("a malformed cursor should be refused")
+	}
+}
+
+func Test_cursorValues(t *testing.T) {
+	var at = time.Date(2024, 3, 1, 12, 30, 0, 5, time.UTC)
+	order := []pouch.OrderColumn{{Column: "DeletedAt"}, {Column: "ID"}}
+	c, err := pouch.CursorFor(&Plant{ID: 3, DeletedAt: &at}, order)
+	if err != nil {
+		t.Fatal("err should have been nil, was: ", err)
+	}
+	vals, err := c.Values()
+	if err != nil {
+		t.Fatal("err should have been nil, was: ", err)
+	}
+	if v, ok := vals[0].(time.Time); !ok || !v.Equal(at) || vals[1] != int64(3) {
+		t.Error("cursors should decode values as the kind they were encoded from, was: ", vals)
+	}
+
+	// too large to tell apart as float64s
+	var big = []pouch.Findable{&Customer{ID: 1<<53 + 1}, &Customer{ID: 1 << 53}}
+	ids := []pouch.OrderColumn{{Column: "ID"}}
+	after, err := pouch.CursorFor(big[1], ids)
+	if err != nil {
+		t.Fatal("err should have been nil, was: ", err)
+	}
+	page, err := pouch.PageEntities(big, ids, after, "", 0, 0)
+	if err != nil {
+		t.Fatal("err should have been nil, was: ", err)
+	}
+	if len(page) != 1 || page[0].(*Customer).ID != 1<<53+1 {
+		t.Error("integers should be compared exactly, found: ", page)
+	}
+
+	next, prev, err := pouch.PageCursors(big, ids, "", "", 0)
+	if err != nil || len(next) != 0 || len(prev) != 0 {
+		t.Error("a page without a limit should have no cursors, had: ", next, prev, err)
+	}
+}
+
+func Test_rawQuery(t *testing.T) {
+	db, fake := newFakeDB()
+	fake.columns = []string{"Name", "ID", "Total"}
+	fake.rows = [][]driver.Value{{"ada", int64(4), int64(99)}, {"grace", int64(7), int64(12)}}
+
+	const report = "with totals as (select CustomerID, sum(Price) as Total from Item group by CustomerID)\n" +
+		"select Name, ID, Total from Customer join totals on ID = CustomerID where Total > ?"
+	p := SQLPouch(db).(pouch.RawQueryable)
+	if _, err := p.RawQuery(&Customer{}, report, 10); err == nil {
+		t.Error("the unknown Total column should have been an error")
+	}
+
+	p = SQLPouch(db, IgnoreUnknownColumns()).(pouch.RawQueryable)
+	res, err := p.RawQuery(&Customer{}, report, 10)
+	if err != nil {
+		t.Fatal("err should have been nil, was: ", err)
+	}
+	if stmt := fake.last(); stmt.query != report || len(stmt.args) != 1 {
+		t.Error("the query should have been run as is, was: ", stmt.query, stmt.args)
+	}
+	if len(res) != 2 {
+		t.Fatal("expected 2 customers, found: ", len(res))
+	}
+	if c := res[1].(*Customer); c.ID != 7 || c.Name != "grace" {
+		t.Error("columns were mapped onto the wrong fields: ", c)
+	}
+}
+
+func Test_immutableQueries(t *testing.T) {
+	db, fake := newFakeDB()
+	fake.columns = []string{"ID", "Name"}
+	active := SQLPouch(db).Where("Active = ?", true).OrderBy("Name")
+
+	var wg sync.WaitGroup
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			var res []pouch.Findable
+			q := active.Where("ID > ?", i).Limit(i + 1)
+			if err := q.Clone().Preload().FindEntities(&Customer{}, &res); err != nil {
+				t.Error("err should have been nil, was: ", err)
+			}
+		}(i)
+	}
+	wg.Wait()
+
+	for _, stmt := range fake.all() {
+		if !strings.Contains(stmt.query, "where Active = ? AND ID > ?\norder by Name\nlimit ") || len(stmt.args) != 2 {
+			t.Error("branches of a shared query should not see each other: ", stmt.query, stmt.args)
+		}
+	}
+
+	var res []pouch.Findable
+	if err := active.FindEntities(&Customer{}, &res); err != nil {
+		t.Fatal("err should have been nil, was: ", err)
+	}
+	if stmt := fake.last(); stmt.query != "select ID,\n  Name\nfrom Customer\nwhere Active = ?\norder by Name\n" {
+		t.Error("the shared query should be left untouched, was: ", stmt.query)
+	}
+}
+
+func Test_scopes(t *testing.T) {
+	db, fake := newFakeDB()
+	fake.columns = []string{"ID", "Name"}
+	active := func(q pouch.Query) pouch.Query { return q.Where("Active = ?", true) }
+	named := func(name string) pouch.Scope {
+		return func(q pouch.Query) pouch.Query { return q.Where("Name = ?", name) }
+	}
+	p := SQLPouch(db, DefaultScopes("Customer", active))
+
+	var res []pouch.Findable
+	if err := p.Scopes(named("ada")).Limit(5).FindEntities(&Customer{}, &res); err != nil {
+		t.Fatal("err should have been nil, was: ", err)
+	}
+	if stmt := fake.last(); !strings.Contains(stmt.query, "where Name = ? AND Active = ?\nlimit 5") {
+		t.Error("both the given and default scopes should apply, was: ", stmt.query)
+	}
+
+	if err := p.Unscoped().FindEntities(&Customer{}, &res); err != nil {
+		t.Fatal("err should have been nil, was: ", err)
+	}
+	if stmt := fake.last(); strings.Contains(stmt.query, "where") {
+		t.Error("an unscoped query should skip default scopes, was: ", stmt.query)
+	}
+
+	if _, err := p.Offset(0).DeleteWhere(&Customer{}); err != nil {
+		t.Fatal("err should have been nil, was: ", err)
+	}
+	if stmt := fake.last(); stmt.query != "delete\nfrom Customer\nwhere Active = ?\n" {
+		t.Error("default scopes should constrain bulk writes, was: ", stmt.query)
+	}
+
+	fake.rows = [][]driver.Value{{int64(1), "ada"}}
+	if err := p.Find(&Customer{ID: 1}); err != nil {
+		t.Fatal("err should have been nil, was: ", err)
+	}
+	if stmt := fake.last(); !strings.Contains(stmt.query, "where ID = ? AND Active = ?") {
+		t.Error("default scopes should constrain finding entities by identity, was: ", stmt.query)
+	}
+
+	if err := p.Delete(&Customer{ID: 1}); err != nil {
+		t.Fatal("err should have been nil, was: ", err)
+	}
+	if stmt := fake.last(); !strings.Contains(stmt.query, "where ID = ? AND Active = ?") {
+		t.Error("default scopes should constrain writing entities by identity, was: ", stmt.query)
+	}
+
+	if err := p.Unscoped().Delete(&Customer{ID: 1}); err != nil {
+		t.Fatal("err should have been nil, was: ", err)
+	}
+	if stmt := fake.last(); strings.Contains(stmt.query, "Active") {
+		t.Error("an unscoped query should skip default scopes, was: ", stmt.query)
+	}
+
+	fake.rows = nil
+	if err := p.Offset(0).FindEntities(&Food{}, &res); err != nil {
+		t.Fatal("err should have been nil, was: ", err)
+	}
+	if stmt := fake.last(); strings.Contains(stmt.query, "Active") {
+		t.Error("default scopes should only apply to their table, was: ", stmt.query)
+	}
+}
+
+type Order struct {
+	ID         int
+	CustomerID int
+	Customer   *Customer
+	Items      []*Item
+}
+
+func (o *Order) IdentifiableFields() ([]string, []interface{}) {
+	return []string{"ID"}, []interface{}{o.ID}
+}
+
+func (o *Order) GetFieldsFor(cols []string) []interface{} {
+	var fields = make([]interface{}, len(cols))
+	for i, col := range cols {
+		switch col {
+		case "ID":
+			fields[i] = &o.ID
+		case "CustomerID":
+			fields[i] = &o.CustomerID
+		}
+	}
+	return fields
+}
+
+func (o *Order) GetAllFields() ([]string, []interface{}) {
+	return []string{"ID", "CustomerID"}, []interface{}{&o.ID, &o.CustomerID}
+}
+
+func (o *Order) Table() string                { return "Orders" }
+func (o *Order) FindableCopy() pouch.Findable { return &Order{} }
+
+func (o *Order) Relations() []pouch.Relation {
+	return []pouch.Relation{
+		{Name: "Customer", Kind: pouch.BelongsTo, Related: &Customer{}, ForeignKey: "CustomerID"},
+		{Name: "Items", Kind: pouch.HasMany, Related: &Item{}, ForeignKey: "OrderID"},
+	}
+}
+
+func (o *Order) SetRelated(name string, related []pouch.Findable) error {
+	switch name {
+	case "Customer":
+		o.Customer = nil
+		if len(related) > 0 {
+			o.Customer = related[0].(*Customer)
+		}
+	case "Items":
+		o.Items = make([]*Item, len(related))
+		for i, f := range related {
+			o.Items[i] = f.(*Item)
+		}
+	default:
+		return errors.New("unknown relation: " + name)
+	}
+	return nil
+}
+
+type Customer struct {
+	ID   int
+	Name string
+}
+
+func (c *Customer) IdentifiableFields() ([]string, []interface{}) {
+	return []string{"ID"}, []interface{}{c.ID}
+}
+
+func (c *Customer) GetFieldsFor(cols []string) []interface{} {
+	var fields = make([]interface{}, len(cols))
+	for i, col := range cols {
+		switch col {
+		case "ID":
+			fields[i] = &c.ID
+		case "Name":
+			fields[i] = &c.Name
+		}
+	}
+	return fields
+}
+
+func (c *Customer) GetAllFields() ([]string, []interface{}) {
+	return []string{"ID", "Name"}, []interface{}{&c.ID, &c.Name}
+}
+
+func (c *Customer) Table() string                { return "Customer" }
+func (c *Customer) FindableCopy() pouch.Findable { return &Customer{} }
+
+type Item struct {
+	ID      int
+	OrderID int
+}
+
+func (i *Item) IdentifiableFields() ([]string, []interface{}) {
+	return []string{"ID"}, []interface{}{i.ID}
+}
+
+func (i *Item) GetFieldsFor(cols []string) []interface{} {
+	var fields = make([]interface{}, len(cols))
+	for j, col := range cols {
+		switch col {
+		case "ID":
+			fields[j] = &i.ID
+		case "OrderID":
+			fields[j] = &i.OrderID
+		}
+	}
+	return fields
+}
+
+func (i *Item) GetAllFields() ([]string, []interface{}) {
+	return []string{"ID", "OrderID"}, []interface{}{&i.ID, &i.OrderID}
+}
+
+func (i *Item) Table() string                { return "Item" }
+func (i *Item) FindableCopy() pouch.Findable { return &Item{} }
+
+// Plant is a soft deleteable, versioned entity.
+type Plant struct {
+	ID        int
+	Name      string
+	DeletedAt *time.Time
+	Revision  int64
+}
+
+func (p *Plant) IdentifiableFields() ([]string, []interface{}) {
+	return []string{"ID"}, []interface{}{p.ID}
+}
+
+func (p *Plant) GetFieldsFor(cols []string) []interface{} {
+	var fields = make([]interface{}, len(cols))
+	for i, col := range cols {
+		switch col {
+		case "ID":
+			fields[i] = &p.ID
+		case "Name":
+			fields[i] = &p.Name
+		case "DeletedAt":
+			fields[i] = &p.DeletedAt
+		}
+	}
+	return fields
+}
+
+func (p *Plant) GetAllFields() ([]string, []interface{}) {
+	return []string{"ID", "Name", "DeletedAt"}, []interface{}{
+		&p.ID, &p.Name, &p.DeletedAt,
+	}
+}
+
+func (p *Plant) FieldsFor(cols []string) []interface{} {
+	var vals = make([]interface{}, len(cols))
+	for i, col := range cols {
+		switch col {
+		case "ID":
+			vals[i] = p.ID
+		case "Name":
+			vals[i] = p.Name
+		case "DeletedAt":
+			vals[i] = p.DeletedAt
+		}
+	}
+	return vals
+}
+
+func (p *Plant) InsertableFields() ([]string, []interface{}) {
+	return []string{"Name"}, []interface{}{p.Name}
+}
+
+func (p *Plant) SetIdentifier(i interface{}) error {
+	id, _ := i.(int64)
+	p.ID = int(id)
+	return nil
+}
+
+func (p *Plant) Table() string           { return "Plant" }
+func (p *Plant) DeletedAtColumn() string { return "DeletedAt" }
+
+func (p *Plant) VersionField() (string, int64) { return "Revision", p.Revision }
+func (p *Plant) SetVersion(v int64)            { p.Revision = v }
+
+func (p *Plant) FindableCopy() pouch.Findable {
+	return &Plant{}
+}
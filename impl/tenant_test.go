@@ -0,0 +1,300 @@
+package impl
+
+import (
+	"database/sql/driver"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/ttacon/pouch"
+)
+
+func Test_tenantSQLPouch(t *testing.T) {
+	db, fake := newFakeDB()
+	fake.lastID = 9
+	fake.respond = func(query string, args []driver.Value) ([]string, [][]driver.Value) {
+		// only note 3 exists, and it is tenant 7's
+		for _, arg := range args {
+			if arg == int64(3) {
+				return []string{"ID", "TenantID", "Body"}, [][]driver.Value{
+					{int64(3), int64(7), "hi"},
+				}
+			}
+		}
+		return []string{"ID", "TenantID", "Body"}, nil
+	}
+	p := pouch.TenantPouch(SQLPouch(db), "TenantID", 7)
+
+	var notes []pouch.Findable
+	if err := p.Where("Body like ?", "%hi%").FindEntities(&Note{}, &notes); err != nil {
+		t.Fatal("err should have been nil, was: ", err)
+	}
+	if stmt := fake.last(); !strings.Contains(stmt.query, "where Body like ? AND TenantID = ?\n") {
+		t.Error("reads should be confined to the tenant, was: ", stmt.query)
+	}
+
+	if err := p.Find(&Note{ID: 3}); err != nil {
+		t.Fatal("err should have been nil, was: ", err)
+	}
+	if stmt := fake.last(); !strings.Contains(stmt.query, "where TenantID = ? AND ID = ?\n") {
+		t.Error("finding by identity should still be confined to the tenant, was: ", stmt.query)
+	}
+
+	var note = &Note{Body: "new"}
+	if err := p.Create(note); err != nil {
+		t.Fatal("err should have been nil, was: ", err)
+	}
+	if stmt := fake.last(); note.TenantID != 7 || len(stmt.args) != 2 || stmt.args[0] != int64(7) {
+		t.Error("created entities should be given the tenant's ID, was: ", note.TenantID, stmt.args)
+	}
+	if err := p.Create(&Note{TenantID: 8}); err != pouch.ErrWrongTenant {
+		t.Error("creating another tenant's entity should be refused, was: ", err)
+	}
+
+	var ran = len(fake.all())
+	if err := p.Update(&Note{ID: 3, TenantID: 8}); err != pouch.ErrWrongTenant {
+		t.Error("updating an entity to another tenant should be refused, was: ", err)
+	}
+	if err := p.Delete(&Note{ID: 4, TenantID: 7}); err != pouch.ErrWrongTenant {
+		t.Error("deleting an entity the tenant doesn't have should be refused, was: ", err)
+	}
+	for _, stmt := range fake.all()[ran:] {
+		if !strings.HasPrefix(stmt.query, "select") {
+			t.Error("nothing should have been written, ran: ", stmt.query)
+		}
+	}
+
+	if err := p.Update(&Note{ID: 3, TenantID: 7, Body: "edited"}); err != nil {
+		t.Fatal("err should have been nil, was: ", err)
+	}
+	if stmt := fake.last(); !strings.HasPrefix(stmt.query, "update Note") {
+		t.Error("the tenant's own entity should have been updated, ran: ", stmt.query)
+	}
+}
+
+func Test_tenantWrites(t *testing.T) {
+	db, fake := newFakeDB()
+	fake.respond = func(query string, args []driver.Value) ([]string, [][]driver.Value) {
+		return []string{"ID", "TenantID", "Body"}, [][]driver.Value{{int64(3), int64(7), "hi"}}
+	}
+	p := pouch.TenantPouch(SQLPouch(db, Strict()), "TenantID", 7)
+
+	if _, err := p.Offset(0).DeleteWhere(&Note{}); err != pouch.ErrUnconstrained {
+		t.Error("deleting by the tenant's criterion alone should be refused, was: ", err)
+	}
+	if _, err := p.Offset(0).UpdateWhere(&Note{}, map[string]interface{}{"Body": ""}); err != pouch.ErrUnconstrained {
+		t.Error("updating by the tenant's criterion alone should be refused, was: ", err)
+	}
+	if _, err := p.Where("Body = ?", "hi").DeleteWhere(&Note{}); err != nil {
+		t.Fatal("err should have been nil, was: ", err)
+	}
+	if stmt := fake.last(); !strings.Contains(stmt.query, "where Body = ? AND TenantID = ?") {
+		t.Error("deleting by criterions should be confined to the tenant, was: ", stmt.query)
+	}
+	if _, err := p.Offset(0).AllowUnconstrained().DeleteWhere(&Note{}); err != nil {
+		t.Fatal("err should have been nil, was: ", err)
+	}
+	if stmt := fake.last(); !strings.Contains(stmt.query, "where TenantID = ?") {
+		t.Error("deleting every entity should still be confined to the tenant, was: ", stmt.query)
+	}
+
+	if err := p.Update(&Note{ID: 3, TenantID: 7, Body: "edited"}); err != nil {
+		t.Fatal("err should have been nil, was: ", err)
+	}
+	if stmt := fake.last(); !strings.Contains(stmt.query, "where ID = ? AND TenantID = ?") {
+		t.Error("writes should be confined to the tenant, was: ", stmt.query)
+	}
+
+	// the note is handed to another tenant once it has been checked
+	fake.affected = 0
+	if err := p.Delete(&Note{ID: 3}); err != pouch.ErrWrongTenant {
+		t.Error("writing an entity that left the tenant should be refused, was: ", err)
+	}
+	if stmt := fake.last(); !strings.Contains(stmt.query, "where ID = ? AND TenantID = ?") {
+		t.Error("writes should be confined to the tenant, was: ", stmt.query)
+	}
+}
+
+func Test_tenantPreload(t *testing.T) {
+	db, fake := newFakeDB()
+	fake.respond = func(query string, args []driver.Value) ([]string, [][]driver.Value) {
+		if strings.Contains(query, "from Reply") {
+			return []string{"ID", "TenantID", "NoteID"}, [][]driver.Value{
+				{int64(5), int64(7), int64(3)}, {int64(6), int64(8), int64(3)},
+			}
+		}
+		return []string{"ID", "TenantID", "Body"}, [][]driver.Value{{int64(3), int64(7), "hi"}}
+	}
+	p := pouch.TenantPouch(SQLPouch(db), "TenantID", 7)
+
+	var notes []pouch.Findable
+	if err := p.Preload("Replies").Offset(0).FindEntities(&Note{}, &notes); err != nil {
+		t.Fatal("err should have been nil, was: ", err)
+	}
+	if stmt := fake.last(); !strings.Contains(stmt.query, "where NoteID in (?) AND TenantID = ?") {
+		t.Error("preloaded relations should be confined to the tenant, was: ", stmt.query)
+	}
+	if len(notes) != 1 || len(notes[0].(*Note).Replies) != 1 || notes[0].(*Note).Replies[0].ID != 5 {
+		t.Error("only the tenant's related entities should have been attached, found: ", notes)
+	}
+}
+
+func Test_tenantDynamicPouch(t *testing.T) {
+	var notes = map[int]*Note{
+		1: {ID: 1, TenantID: 7, Body: "mine"},
+		2: {ID: 2, TenantID: 8, Body: "theirs"},
+	}
+	d := NewDynamicPouch(notes)
+	d.SetFind(func(f pouch.Findable, i interface{}) error {
+		n := f.(*Note)
+		stored, ok := i.(map[int]*Note)[n.ID]
+		if !ok {
+			return errors.New("no such note")
+		}
+		*n = *stored
+		return nil
+	})
+	d.SetFindEntities(func(template pouch.Findable, res *[]pouch.Findable, i interface{}) error {
+		for _, n := range i.(map[int]*Note) {
+			cop := *n
+			*res = append(*res, &cop)
+		}
+		return nil
+	})
+	var deleted []int
+	d.SetDlete(func(del pouch.Deleteable, i interface{}) error {
+		deleted = append(deleted, del.(*Note).ID)
+		return nil
+	})
+	p := pouch.TenantPouch(d, "TenantID", 7)
+
+	var found []pouch.Findable
+	if err := p.Offset(0).FindEntities(&Note{}, &found); err != nil {
+		t.Fatal("err should have been nil, was: ", err)
+	}
+	if len(found) != 1 || found[0].(*Note).Body != "mine" {
+		t.Error("only the tenant's entities should have been found, found: ", found)
+	}
+
+	if err := p.Find(&Note{ID: 2}); err != pouch.ErrWrongTenant {
+		t.Error("finding another tenant's entity should be refused, was: ", err)
+	}
+	if err := p.Delete(&Note{ID: 2}); err != pouch.ErrWrongTenant {
+		t.Error("deleting another tenant's entity should be refused, was: ", err)
+	}
+	if err := p.Delete(&Note{ID: 1}); err != nil {
+		t.Fatal("err should have been nil, was: ", err)
+	}
+	if len(deleted) != 1 || deleted[0] != 1 {
+		t.Error("only the tenant's entity should have been deleted, deleted: ", deleted)
+	}
+}
+
+type Note struct {
+	ID       int
+	TenantID int
+	Body     string
+	Replies  []*Reply
+}
+
+func (n *Note) IdentifiableFields() ([]string, []interface{}) {
+	return []string{"ID"}, []interface{}{n.ID}
+}
+
+func (n *Note) GetFieldsFor(cols []string) []interface{} {
+	var fields = make([]interface{}, len(cols))
+	for i, col := range cols {
+		switch col {
+		case "ID":
+			fields[i] = &n.ID
+		case "TenantID":
+			fields[i] = &n.TenantID
+		case "Body":
+			fields[i] = &n.Body
+		}
+	}
+	return fields
+}
+
+func (n *Note) GetAllFields() ([]string, []interface{}) {
+	return []string{"ID", "TenantID", "Body"}, []interface{}{
+		&n.ID, &n.TenantID, &n.Body,
+	}
+}
+
+func (n *Note) FieldsFor(cols []string) []interface{} {
+	var vals = make([]interface{}, len(cols))
+	for i, col := range cols {
+		switch col {
+		case "ID":
+			vals[i] = n.ID
+		case "TenantID":
+			vals[i] = n.TenantID
+		case "Body":
+			vals[i] = n.Body
+		}
+	}
+	return vals
+}
+
+func (n *Note) InsertableFields() ([]string, []interface{}) {
+	return []string{"TenantID", "Body"}, []interface{}{n.TenantID, n.Body}
+}
+
+func (n *Note) SetIdentifier(i interface{}) error {
+	id, _ := i.(int64)
+	n.ID = int(id)
+	return nil
+}
+
+func (n *Note) Table() string                { return "Note" }
+func (n *Note) FindableCopy() pouch.Findable { return &Note{} }
+
+func (n *Note) Relations() []pouch.Relation {
+	return []pouch.Relation{
+		{Name: "Replies", Kind: pouch.HasMany, Related: &Reply{}, ForeignKey: "NoteID"},
+	}
+}
+
+func (n *Note) SetRelated(name string, related []pouch.Findable) error {
+	if name != "Replies" {
+		return errors.New("unknown relation: " + name)
+	}
+	n.Replies = make([]*Reply, len(related))
+	for i, f := range related {
+		n.Replies[i] = f.(*Reply)
+	}
+	return nil
+}
+
+type Reply struct {
+	ID       int
+	TenantID int
+	NoteID   int
+}
+
+func (r *Reply) IdentifiableFields() ([]string, []interface{}) {
+	return []string{"ID"}, []interface{}{r.ID}
+}
+
+func (r *Reply) GetFieldsFor(cols []string) []interface{} {
+	var fields = make([]interface{}, len(cols))
+	for i, col := range cols {
+		switch col {
+		case "ID":
+			fields[i] = &r.ID
+		case "TenantID":
+			fields[i] = &r.TenantID
+		case "NoteID":
+			fields[i] = &r.NoteID
+		}
+	}
+	return fields
+}
+
+func (r *Reply) GetAllFields() ([]string, []interface{}) {
+	return []string{"ID", "TenantID", "NoteID"}, []interface{}{&r.ID, &r.TenantID, &r.NoteID}
+}
+
+func (r *Reply) Table() string                { return "Reply" }
+func (r *Reply) FindableCopy() pouch.Findable { return &Reply{} }
@@ -0,0 +1,54 @@
+package impl
+
+import (
+	"context"
+	"database/sql/driver"
+	"errors"
+	"testing"
+
+	"github.com/ttacon/pouch"
+)
+
+func Test_traced(t *testing.T) {
+	db, fake := newFakeDB()
+	fake.columns = []string{"ID", "TenantID", "Body"}
+	fake.rows = [][]driver.Value{{int64(1), int64(7), "hi"}, {int64(2), int64(7), "there"}}
+
+	var tracer = &pouch.MemoryTracer{}
+	p := pouch.Traced(SQLPouch(db, Redact("Note", "Body")), tracer)
+	ctx, parent := tracer.Start(context.Background(), "request")
+
+	var notes []pouch.Findable
+	if err := p.WithContext(ctx).Where("Body = ?", "hi").FindEntities(&Note{}, &notes); err != nil {
+		t.Fatal("err should have been nil, was: ", err)
+	}
+	fake.err = errors.New("gone away")
+	p.Create(&Note{Body: "hunter2"})
+	parent.End()
+
+	spans := tracer.Spans()
+	if len(spans) != 3 {
+		t.Fatal("expected a span per operation, had: ", len(spans))
+	}
+	found, created := spans[1], spans[2]
+	if found.Name != "pouch.FindEntities" || found.Parent != spans[0] || found.EndTime.IsZero() {
+		t.Error("unexpected span for finding entities: ", found)
+	}
+	for attr, expected := range map[string]interface{}{
+		pouch.AttrOperation: "FindEntities",
+		pouch.AttrTable:     "Note",
+		pouch.AttrRows:      int64(2),
+		pouch.AttrStatement: fake.all()[0].query,
+	} {
+		if found.Attributes[attr] != expected {
+			t.Errorf("expected %s to be %v, was: %v", attr, expected, found.Attributes[attr])
+		}
+	}
+
+	if created.Parent != nil || len(created.Errors) != 1 || created.Errors[0] != fake.err {
+		t.Error("the error of an operation should be recorded on its span, had: ", created.Errors)
+	}
+	if _, ok := created.Attributes[pouch.AttrRows]; ok {
+		t.Error("failed operations should not report rows")
+	}
+}
@@ -0,0 +1,40 @@
+package pouch
+
+import "sync"
+
+// A Lazy can be embedded in an entity to memoize the related entities
+// retrieved by its (generated) lazy loaders, so that each is only
+// retrieved on first access.
+type Lazy struct {
+	mu     sync.Mutex
+	loaded map[string]lazyEntry
+}
+
+type lazyEntry struct {
+	key    string
+	entity Findable
+}
+
+// Load returns the entity memoized under the given name, retrieving it
+// with find on first access. The key is the foreign key the entity was
+// retrieved by, if it has changed since, the entity is retrieved again.
+// Keys held in pointers are compared by the values they point to.
+func (l *Lazy) Load(name string, key interface{}, find func() (Findable, error)) (Findable, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	key, _ = deref(key)
+	if entry, ok := l.loaded[name]; ok && entry.key == keyOf(key) {
+		return entry.entity, nil
+	}
+
+	f, err := find()
+	if err != nil {
+		return nil, err
+	}
+	if l.loaded == nil {
+		l.loaded = make(map[string]lazyEntry)
+	}
+	l.loaded[name] = lazyEntry{key: keyOf(key), entity: f}
+	return f, nil
+}
@@ -0,0 +1,233 @@
+package pouch
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// A MetricsRegistry makes the metrics an Instrumented pouch records, it
+// is meant to be adapted to a metrics library, see the prom package for
+// an adapter to the Prometheus client's CounterVec and HistogramVec, and
+// MemoryRegistry for one that keeps the metrics itself.
+type MetricsRegistry interface {
+	Counter(name, help string, labels ...string) Counter
+	Histogram(name, help string, buckets []float64, labels ...string) Histogram
+}
+
+// A Counter is a metric that only goes up, it's given a value for every
+// label it was made with, in order.
+type Counter interface {
+	Add(delta float64, labelValues ...string)
+}
+
+// A Histogram counts the values it observes in buckets, it's given a
+// value for every label it was made with, in order.
+type Histogram interface {
+	Observe(value float64, labelValues ...string)
+}
+
+// The metrics an Instrumented pouch records, labeled by operation (i.e.
+// "Find") and table.
+const (
+	MetricOperations = "pouch_operations_total"
+	// MetricErrors is also labeled by the class of the error, see
+	// ErrorClass.
+	MetricErrors = "pouch_errors_total"
+	// MetricDuration is the latency of operations, in seconds.
+	MetricDuration = "pouch_operation_duration_seconds"
+	// MetricRows counts the entities operations found, created or wrote
+	// by criterions; operations that write entities by their identity
+	// can't tell how many they wrote, and don't count.
+	MetricRows = "pouch_rows_total"
+)
+
+// DurationBuckets are the buckets, in seconds, of the latency histogram
+// of an Instrumented pouch.
+var DurationBuckets = []float64{.001, .005, .01, .025, .05, .1, .25, .5, 1, 2.5, 5, 10}
+
+// Instrumented returns a view of the given Pouch which records metrics
+// of every operation that reads or writes entities in the given
+// registry: how many ran, how many failed, how long they took and how
+// many entities they found or wrote (see MetricRows).
+func Instrumented(p Pouch, r MetricsRegistry) Pouch {
+	var (
+		labels = []string{"operation", "table"}
+		ops    = r.Counter(MetricOperations, "Operations run by the pouch.", labels...)
+		errs   = r.Counter(MetricErrors, "Operations of the pouch that failed, by the class of their error.",
+			"operation", "table", "class")
+		latency = r.Histogram(MetricDuration, "How long operations of the pouch took, in seconds.",
+			DurationBuckets, labels...)
+		rows = r.Counter(MetricRows, "Entities the operations of the pouch found or wrote.", labels...)
+	)
+	return newObservedQuery(blank(p), func(q Query, op, table string) (Query, func(int64, error)) {
+		start := time.Now()
+		return q, func(n int64, err error) {
+			latency.Observe(time.Since(start).Seconds(), op, table)
+			ops.Add(1, op, table)
+			if err != nil {
+				errs.Add(1, op, table, ErrorClass(err))
+				return
+			}
+			if n != unknownRows {
+				rows.Add(float64(n), op, table)
+			}
+		}
+	})
+}
+
+// ErrorClass returns the class of an error of an operation, for metrics:
+// "not_found", "conflict", "permission", "wrong_tenant",
+// "unconstrained", "overloaded", "circuit_open", "canceled", "timeout"
+// or otherwise "other".
+func ErrorClass(err error) string {
+	var (
+		notFound   *NotFoundError
+		conflict   *ConflictError
+		permission *PermissionError
+		overloaded *OverloadedError
+	)
+	switch {
+	case errors.Is(err, sql.ErrNoRows), errors.As(err, &notFound):
+		return "not_found"
+	case errors.As(err, &conflict):
+		return "conflict"
+	case errors.As(err, &permission):
+		return "permission"
+	case errors.Is(err, ErrWrongTenant):
+		return "wrong_tenant"
+	case errors.Is(err, ErrUnconstrained):
+		return "unconstrained"
+	case errors.As(err, &overloaded):
+		return "overloaded"
+	case errors.Is(err, ErrCircuitOpen):
+		return "circuit_open"
+	case errors.Is(err, context.Canceled):
+		return "canceled"
+	case errors.Is(err, context.DeadlineExceeded):
+		return "timeout"
+	}
+	return "other"
+}
+
+// MemoryRegistry is a MetricsRegistry which keeps the metrics it makes,
+// for tests. Its zero value is ready to use.
+type MemoryRegistry struct {
+	mu      sync.Mutex
+	metrics map[string]*metric
+}
+
+// metric is a counter or histogram made by a MemoryRegistry, which keeps
+// a series for every combination of label values it's given.
+type metric struct {
+	buckets []float64
+	series  map[string]*series
+	// guards series, and the series in it
+	mu *sync.Mutex
+}
+
+type series struct {
+	labelValues []string
+	// the value of a counter, or the sum of a histogram's observations
+	value  float64
+	count  uint64
+	counts []uint64
+}
+
+func (r *MemoryRegistry) metric(name string, buckets []float64) *metric {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.metrics == nil {
+		r.metrics = make(map[string]*metric)
+	}
+	if m, ok := r.metrics[name]; ok {
+		return m
+	}
+	m := &metric{
+		buckets: append([]float64(nil), buckets...),
+		series:  make(map[string]*series),
+		mu:      &r.mu,
+	}
+	sort.Float64s(m.buckets)
+	r.metrics[name] = m
+	return m
+}
+
+func (r *MemoryRegistry) Counter(name, help string, labels ...string) Counter {
+	return r.metric(name, nil)
+}
+
+func (r *MemoryRegistry) Histogram(name, help string, buckets []float64, labels ...string) Histogram {
+	return r.metric(name, buckets)
+}
+
+// get returns the series for the given label values, the metric's
+// mutex must be held.
+func (m *metric) get(labelValues []string) *series {
+	key := strings.Join(labelValues, "\x00")
+	s, ok := m.series[key]
+	if !ok {
+		s = &series{
+			labelValues: append([]string(nil), labelValues...),
+			counts:      make([]uint64, len(m.buckets)),
+		}
+		m.series[key] = s
+	}
+	return s
+}
+
+func (m *metric) Add(delta float64, labelValues ...string) {
+	m.mu.Lock()
+	m.get(labelValues).value += delta
+	m.mu.Unlock()
+}
+
+func (m *metric) Observe(value float64, labelValues ...string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	s := m.get(labelValues)
+	s.value += value
+	s.count++
+	for i, upper := range m.buckets {
+		if value <= upper {
+			s.counts[i]++
+		}
+	}
+}
+
+// Value returns the value of a counter, or the sum of the values a
+// histogram observed, for the given label values.
+func (r *MemoryRegistry) Value(name string, labelValues ...string) float64 {
+	if s := r.lookup(name, labelValues); s != nil {
+		return s.value
+	}
+	return 0
+}
+
+// Count returns how many values a histogram observed for the given label
+// values.
+func (r *MemoryRegistry) Count(name string, labelValues ...string) uint64 {
+	if s := r.lookup(name, labelValues); s != nil {
+		return s.count
+	}
+	return 0
+}
+
+func (r *MemoryRegistry) lookup(name string, labelValues []string) *series {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	m, ok := r.metrics[name]
+	if !ok {
+		return nil
+	}
+	s, ok := m.series[strings.Join(labelValues, "\x00")]
+	if !ok {
+		return nil
+	}
+	var cop = *s
+	return &cop
+}
@@ -0,0 +1,151 @@
+package pouch
+
+// An observer is told of every operation of an observedQuery that reads
+// or writes entities, before it runs: it's given the Query the operation
+// is about to run on, and returns the Query to run it on instead and a
+// func to call with how many entities it found or wrote (or unknownRows),
+// and its error.
+type observer func(q Query, op, table string) (Query, func(rows int64, err error))
+
+// unknownRows is reported for the operations that write entities by
+// their identity, which can't tell how many they wrote: pouches that
+// aren't strict skip the entities that are missing without an error.
+const unknownRows = -1
+
+// observedQuery is the base of the Pouches in this package that watch
+// operations go by without changing them (i.e. Traced).
+type observedQuery struct {
+	decorator
+	observe observer
+}
+
+func newObservedQuery(inner Query, o observer) *observedQuery {
+	return &observedQuery{
+		decorator: decorator{
+			inner: inner,
+			wrap: func(q Query) Query {
+				return newObservedQuery(q, o)
+			},
+		},
+		observe: o,
+	}
+}
+
+// firstTable is the table of the first of the given entities.
+func firstTable(es interface{}) string {
+	var first Tableable
+	switch es := es.(type) {
+	case []Findable:
+		if len(es) > 0 {
+			first = es[0]
+		}
+	case []Createable:
+		if len(es) > 0 {
+			first = es[0]
+		}
+	case []Updateable:
+		if len(es) > 0 {
+			first = es[0]
+		}
+	case []Deleteable:
+		if len(es) > 0 {
+			first = es[0]
+		}
+	}
+	if first == nil {
+		return ""
+	}
+	return first.Table()
+}
+
+func (s *observedQuery) Find(i Findable) (err error) {
+	q, done := s.observe(s.inner, "Find", i.Table())
+	defer func() { done(1, err) }()
+	return q.Find(i)
+}
+
+func (s *observedQuery) FindAll(fs []Findable) (err error) {
+	q, done := s.observe(s.inner, "FindAll", firstTable(fs))
+	defer func() { done(int64(len(fs)), err) }()
+	return q.FindAll(fs)
+}
+
+func (s *observedQuery) FindEntities(template Findable, res *[]Findable) (err error) {
+	var found = len(*res)
+	q, done := s.observe(s.inner, "FindEntities", template.Table())
+	defer func() { done(int64(len(*res)-found), err) }()
+	return q.FindEntities(template, res)
+}
+
+func (s *observedQuery) FindPage(template Findable, res *[]Findable) (next, prev Cursor, err error) {
+	var found = len(*res)
+	q, done := s.observe(s.inner, "FindPage", template.Table())
+	defer func() { done(int64(len(*res)-found), err) }()
+	return q.FindPage(template, res)
+}
+
+func (s *observedQuery) Create(c Createable) (err error) {
+	q, done := s.observe(s.inner, "Create", c.Table())
+	defer func() { done(1, err) }()
+	return q.Create(c)
+}
+
+func (s *observedQuery) CreateAll(cs []Createable) (err error) {
+	q, done := s.observe(s.inner, "CreateAll", firstTable(cs))
+	defer func() { done(int64(len(cs)), err) }()
+	return q.CreateAll(cs)
+}
+
+func (s *observedQuery) Update(u Updateable) (err error) {
+	q, done := s.observe(s.inner, "Update", u.Table())
+	defer func() { done(unknownRows, err) }()
+	return q.Update(u)
+}
+
+func (s *observedQuery) UpdateColumns(u Updateable, cols ...string) (err error) {
+	q, done := s.observe(s.inner, "UpdateColumns", u.Table())
+	defer func() { done(unknownRows, err) }()
+	return q.UpdateColumns(u, cols...)
+}
+
+func (s *observedQuery) UpdateAll(us []Updateable) (err error) {
+	q, done := s.observe(s.inner, "UpdateAll", firstTable(us))
+	defer func() { done(unknownRows, err) }()
+	return q.UpdateAll(us)
+}
+
+func (s *observedQuery) Delete(d Deleteable) (err error) {
+	q, done := s.observe(s.inner, "Delete", d.Table())
+	defer func() { done(unknownRows, err) }()
+	return q.Delete(d)
+}
+
+func (s *observedQuery) DeleteAll(ds []Deleteable) (err error) {
+	q, done := s.observe(s.inner, "DeleteAll", firstTable(ds))
+	defer func() { done(unknownRows, err) }()
+	return q.DeleteAll(ds)
+}
+
+func (s *observedQuery) HardDelete(d Deleteable) (err error) {
+	q, done := s.observe(s.inner, "HardDelete", d.Table())
+	defer func() { done(unknownRows, err) }()
+	return q.HardDelete(d)
+}
+
+func (s *observedQuery) Restore(sd SoftDeleteable) (err error) {
+	q, done := s.observe(s.inner, "Restore", sd.Table())
+	defer func() { done(unknownRows, err) }()
+	return q.Restore(sd)
+}
+
+func (s *observedQuery) UpdateWhere(t Tableable, assignments map[string]interface{}) (n int64, err error) {
+	q, done := s.observe(s.inner, "UpdateWhere", t.Table())
+	defer func() { done(n, err) }()
+	return q.UpdateWhere(t, assignments)
+}
+
+func (s *observedQuery) DeleteWhere(t Tableable) (n int64, err error) {
+	q, done := s.observe(s.inner, "DeleteWhere", t.Table())
+	defer func() { done(n, err) }()
+	return q.DeleteWhere(t)
+}
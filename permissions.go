@@ -0,0 +1,270 @@
+package pouch
+
+import (
+	"fmt"
+	"strings"
+)
+
+// An Operation is a kind of access to the entities of a table.
+type Operation int
+
+const (
+	// OpRead covers Find, FindAll, FindEntities and FindPage.
+	OpRead Operation = 1 << iota
+	// OpCreate covers Create and CreateAll.
+	OpCreate
+	// OpUpdate covers Update, UpdateColumns, UpdateAll, UpdateWhere and
+	// Restore.
+	OpUpdate
+	// OpDelete covers Delete, DeleteAll, HardDelete and DeleteWhere.
+	OpDelete
+)
+
+func (o Operation) String() string {
+	var names []string
+	for _, op := range []struct {
+		op   Operation
+		name string
+	}{
+		{OpRead, "read"},
+		{OpCreate, "create"},
+		{OpUpdate, "update"},
+		{OpDelete, "delete"},
+	} {
+		if o&op.op != 0 {
+			names = append(names, op.name)
+		}
+	}
+	if len(names) == 0 {
+		return "none"
+	}
+	return strings.Join(names, "|")
+}
+
+// A Policy decides which operations are allowed on which tables.
+type Policy interface {
+	Allows(table string, op Operation) bool
+}
+
+// Permissions is a Policy granting operations by table, where the
+// operations granted for "*" are granted for every table, i.e.
+//
+//	Permissions{"*": OpRead, "Comment": OpCreate | OpUpdate}
+type Permissions map[string]Operation
+
+func (p Permissions) Allows(table string, op Operation) bool {
+	return (p[table]|p["*"])&op == op
+}
+
+// A PermissionError is returned for operations a Policy doesn't allow.
+type PermissionError struct {
+	Table string
+	Op    Operation
+}
+
+func (p *PermissionError) Error() string {
+	return fmt.Sprintf("permission denied: cannot %s %s", p.Op, p.Table)
+}
+
+// ReadOnly returns a view of the given Pouch which can only read.
+func ReadOnly(p Pouch) Pouch {
+	return WithPolicy(p, Permissions{"*": OpRead})
+}
+
+// WriteOnly returns a view of the given Pouch which can only create and
+// update entities.
+func WriteOnly(p Pouch) Pouch {
+	return WithPolicy(p, Permissions{"*": OpCreate | OpUpdate})
+}
+
+// DeleteOnly returns a view of the given Pouch which can only delete.
+func DeleteOnly(p Pouch) Pouch {
+	return WithPolicy(p, Permissions{"*": OpDelete})
+}
+
+// WithPolicy returns a view of the given Pouch which fails operations the
+// given Policy doesn't allow with a *PermissionError, before they reach
+// the Pouch. Relations preloaded for the entities that are found are
+// read through the view as well, so reading them takes OpRead on their
+// tables.
+func WithPolicy(p Pouch, policy Policy) Pouch {
+	s := &policyQuery{
+		pouch:  p,
+		policy: policy,
+	}
+	return s.with(blank(p))
+}
+
+type policyQuery struct {
+	decorator
+	// the pouch, for preloading relations
+	pouch    Pouch
+	policy   Policy
+	preloads []string
+}
+
+// with returns a copy of the query, wrapping the given one.
+func (s *policyQuery) with(inner Query) *policyQuery {
+	c := *s
+	c.preloads = c.preloads[:len(c.preloads):len(c.preloads)]
+	c.decorator = decorator{
+		inner: inner,
+		wrap: func(q Query) Query {
+			return c.with(q)
+		},
+		within: func(tx Pouch) Query {
+			return WithPolicy(tx, c.policy).(Query)
+		},
+	}
+	return &c
+}
+
+// Preload has the query preload the relations itself, through the view
+// of the pouch, rather than the wrapped query which would find them
+// through the bare pouch.
+func (s *policyQuery) Preload(relations ...string) Query {
+	q := s.with(s.inner)
+	q.preloads = append(q.preloads, relations...)
+	return q
+}
+
+// preload preloads the relations of the entities found past the given
+// index.
+func (s *policyQuery) preload(res *[]Findable, from int) error {
+	if len(s.preloads) == 0 {
+		return nil
+	}
+	return Preload(WithPolicy(s.pouch, s.policy).WithContext(s.inner.Context()), (*res)[from:], s.preloads...)
+}
+
+func (s *policyQuery) allow(t Tableable, op Operation) error {
+	if s.policy.Allows(t.Table(), op) {
+		return nil
+	}
+	return &PermissionError{Table: t.Table(), Op: op}
+}
+
+func (s *policyQuery) Find(i Findable) error {
+	if err := s.allow(i, OpRead); err != nil {
+		return err
+	}
+	return s.inner.Find(i)
+}
+
+func (s *policyQuery) FindAll(fs []Findable) error {
+	for _, f := range fs {
+		if err := s.allow(f, OpRead); err != nil {
+			return err
+		}
+	}
+	return s.inner.FindAll(fs)
+}
+
+func (s *policyQuery) FindEntities(template Findable, res *[]Findable) error {
+	if err := s.allow(template, OpRead); err != nil {
+		return err
+	}
+	var found = len(*res)
+	if err := s.inner.FindEntities(template, res); err != nil {
+		return err
+	}
+	return s.preload(res, found)
+}
+
+func (s *policyQuery) FindPage(template Findable, res *[]Findable) (Cursor, Cursor, error) {
+	if err := s.allow(template, OpRead); err != nil {
+		return "", "", err
+	}
+	var found = len(*res)
+	next, prev, err := s.inner.FindPage(template, res)
+	if err != nil {
+		return "", "", err
+	}
+	if err := s.preload(res, found); err != nil {
+		return "", "", err
+	}
+	return next, prev, nil
+}
+
+func (s *policyQuery) Create(c Createable) error {
+	if err := s.allow(c, OpCreate); err != nil {
+		return err
+	}
+	return s.inner.Create(c)
+}
+
+func (s *policyQuery) CreateAll(cs []Createable) error {
+	for _, c := range cs {
+		if err := s.allow(c, OpCreate); err != nil {
+			return err
+		}
+	}
+	return s.inner.CreateAll(cs)
+}
+
+func (s *policyQuery) Update(u Updateable) error {
+	if err := s.allow(u, OpUpdate); err != nil {
+		return err
+	}
+	return s.inner.Update(u)
+}
+
+func (s *policyQuery) UpdateColumns(u Updateable, cols ...string) error {
+	if err := s.allow(u, OpUpdate); err != nil {
+		return err
+	}
+	return s.inner.UpdateColumns(u, cols...)
+}
+
+func (s *policyQuery) UpdateAll(us []Updateable) error {
+	for _, u := range us {
+		if err := s.allow(u, OpUpdate); err != nil {
+			return err
+		}
+	}
+	return s.inner.UpdateAll(us)
+}
+
+func (s *policyQuery) Delete(d Deleteable) error {
+	if err := s.allow(d, OpDelete); err != nil {
+		return err
+	}
+	return s.inner.Delete(d)
+}
+
+func (s *policyQuery) DeleteAll(ds []Deleteable) error {
+	for _, d := range ds {
+		if err := s.allow(d, OpDelete); err != nil {
+			return err
+		}
+	}
+	return s.inner.DeleteAll(ds)
+}
+
+func (s *policyQuery) HardDelete(d Deleteable) error {
+	if err := s.allow(d, OpDelete); err != nil {
+		return err
+	}
+	return s.inner.HardDelete(d)
+}
+
+func (s *policyQuery) Restore(sd SoftDeleteable) error {
+	if err := s.allow(sd, OpUpdate); err != nil {
+		return err
+	}
+	return s.inner.Restore(sd)
+}
+
+func (s *policyQuery) UpdateWhere(t Tableable, assignments map[string]interface{}) (int64, error) {
+	if err := s.allow(t, OpUpdate); err != nil {
+		return 0, err
+	}
+	return s.inner.UpdateWhere(t, assignments)
+}
+
+func (s *policyQuery) DeleteWhere(t Tableable) (int64, error) {
+	if err := s.allow(t, OpDelete); err != nil {
+		return 0, err
+	}
+	return s.inner.DeleteWhere(t)
+}
@@ -1,6 +1,9 @@
 package pouch
 
-import "database/sql"
+import (
+	"context"
+	"database/sql"
+)
 
 // A Pouch is anything which can act as a backing Storage and which
 // we can query for entities.
@@ -9,6 +12,12 @@ type Pouch interface {
 	Storage
 }
 
+// RawQueryable is implemented by pouches which can run hand-written
+// queries, mapping the columns they return onto copies of a template.
+type RawQueryable interface {
+	RawQuery(template Findable, query string, args ...interface{}) ([]Findable, error)
+}
+
 // Storage is the interface implemented by anything which can
 // be interacted with to store or retrieve entities which
 // can be found, created, updated and deleted. For specific
@@ -42,6 +51,9 @@ type Storage interface {
 	// functionality is a full update or a partial update, is up to
 	// underlying Pouch implementation.
 	Update(Updateable) error
+	// UpdateColumns updates only the given columns of the Updateable
+	// entity, with the values it provides for them through FieldsFor.
+	UpdateColumns(u Updateable, cols ...string) error
 	// UpdateAll updates all of the provided entities in the backing
 	// storage media. Like [Find/Create]All, the underlying types do not
 	// have to be the same and as such this function is not meant to
@@ -58,9 +70,9 @@ type Storage interface {
 
 // A Query is a direct gateway to interact with the storage and
 // retrieval of entities which is backed by a storage system.
-// A Query can also be modified to either add or remove criterions
-// that are used to filter searches for entities in the backing
-// storage system.
+// A Query can also be built upon to add criterions that are used to
+// filter searches for entities in the backing storage system, which
+// returns a new Query and leaves the original untouched.
 type Query interface {
 	Queryable
 	Storage
@@ -69,6 +81,38 @@ type Query interface {
 	// current criterions. This can thus also be used to retrieve
 	// all entities of a given type, to implement pagination, etc.
 	FindEntities(Findable, *[]Findable) error
+	// FindPage is FindEntities for keyset pagination: it also returns
+	// the cursors for the pages of results after and before the one
+	// found, either of which is empty if there is no such page.
+	FindPage(Findable, *[]Findable) (next, prev Cursor, err error)
+
+	// HardDelete permanently removes the given entity from the backing
+	// storage medium, even if it is SoftDeleteable.
+	HardDelete(Deleteable) error
+	// Restore undoes the soft deletion of the given entity.
+	Restore(SoftDeleteable) error
+
+	// UpdateWhere sets the given columns to the given values for every
+	// entity in the Tableable's table that satisfies the Query's current
+	// criterions, returning how many entities were updated.
+	UpdateWhere(t Tableable, assignments map[string]interface{}) (int64, error)
+	// DeleteWhere deletes every entity in the Tableable's table that
+	// satisfies the Query's current criterions, returning how many
+	// entities were deleted.
+	DeleteWhere(t Tableable) (int64, error)
+	// AllowUnconstrained lets UpdateWhere and DeleteWhere run without
+	// any criterions, i.e. against a whole table, without it they
+	// return ErrUnconstrained.
+	AllowUnconstrained() Query
+
+	// Context returns the Query's context, context.Background() unless
+	// it was given one with WithContext.
+	Context() context.Context
+
+	// Clone returns a copy of the Query. Queries are never modified in
+	// place, every criterion added returns a new Query, so a Query can
+	// be shared and built upon by many goroutines.
+	Clone() Query
 }
 
 // A Creatable entity is one that knows where it is meant to be
@@ -124,6 +168,15 @@ type Updateable interface {
 	Tableable
 }
 
+// A Versioned entity is one whose updates are guarded by a version
+// column, an update only succeeds if the version in the Storage system
+// is still the one the entity was read with, after which its version
+// is incremented. Otherwise a *ConflictError is returned.
+type Versioned interface {
+	VersionField() (column string, version int64)
+	SetVersion(int64)
+}
+
 // A Deleteable entity is one which knows how to delete only itself
 // from a Storage system.
 type Deleteable interface {
@@ -131,6 +184,15 @@ type Deleteable interface {
 	Tableable
 }
 
+// A SoftDeleteable entity is one which is never removed from a Storage
+// system when it is deleted, instead the column it names is set to the
+// time of its deletion. Soft deleted entities are hidden from every
+// read unless a Query asks for them with WithDeleted or OnlyDeleted.
+type SoftDeleteable interface {
+	Deleteable
+	DeletedAtColumn() string
+}
+
 // Anything that is Queryable knows how to filter queries for itself.
 type Queryable interface {
 	GroupBy(spec string) Query
@@ -138,8 +200,41 @@ type Queryable interface {
 	Where(frag string, val ...interface{}) Query
 	Limit(lim int) Query
 	Offset(off int) Query
+
+	// WithDeleted includes soft deleted entities in the Query's results.
+	WithDeleted() Query
+	// OnlyDeleted restricts the Query's results to soft deleted entities.
+	OnlyDeleted() Query
+
+	// Preload retrieves the named relations of every entity found by
+	// FindEntities, see Preload and Relatable.
+	Preload(relations ...string) Query
+
+	// After restricts the Query's results to those that come after the
+	// given cursor in its order, see Query.FindPage.
+	After(c Cursor) Query
+	// Before restricts the Query's results to those that come before
+	// the given cursor in its order, see Query.FindPage.
+	Before(c Cursor) Query
+
+	// Scopes applies the given scopes to the Query, in order.
+	Scopes(scopes ...Scope) Query
+	// Unscoped stops the Query from applying the default scopes of the
+	// tables it reads from or writes to.
+	Unscoped() Query
+
+	// WithContext sets the context the Query's operations are done for,
+	// i.e. on behalf of which caller, see WithCaller.
+	WithContext(ctx context.Context) Query
 }
 
+// A Scope is a reusable set of criterions, i.e.
+//
+//	func Active(q Query) Query {
+//		return q.Where("deleted_at is null").Where("active = ?", true)
+//	}
+type Scope func(Query) Query
+
 // Executor is a convenience wrapper that allows both *sql.DB and
 // *sql.DB to be as Pouches.
 type Executor interface {
@@ -6,6 +6,15 @@ type StructInfo struct {
 	Fields            []FieldInfo
 	IDField           string
 	HasAutoGenIDField bool
+	DeletedAtColumn   string
+	VersionColumn     string
+	VersionField      string
+	VersionType       string
+	Relations         []RelationInfo
+	LazyLoaders       []LazyInfo
+	Collections       []CollectionInfo
+	HasLazy           bool
+	Scopes            []ScopeInfo
 }
 
 type FieldInfo struct {
@@ -13,5 +22,47 @@ type FieldInfo struct {
 	Column       string
 	IsPrimaryKey bool
 	IsPointer    bool
+	IsDeletedAt  bool
+	IsVersion    bool
 	Type         string
+
+	// for foreign keys, i.e. `pouch:"belongsTo=Customer"`
+	BelongsTo  string
+	References string
+	Inverse    string
+}
+
+type RelationInfo struct {
+	Name       string
+	Kind       string
+	Type       string
+	ForeignKey string
+	References string
+}
+
+// LazyInfo describes a lazy loader for the entity a foreign key refers to.
+type LazyInfo struct {
+	Name       string
+	Type       string
+	Field      string
+	References string
+	// whether the foreign key is a pointer, which refers to nothing
+	// when nil
+	IsPointer bool
+}
+
+// CollectionInfo describes an accessor for the entities whose foreign
+// keys refer to an entity.
+type CollectionInfo struct {
+	Name       string
+	ForeignKey string
+	Field      string
+}
+
+// ScopeInfo describes a named scope declared in an entity's doc comment,
+// i.e. `// pouch:scope Active deleted_at is null`.
+type ScopeInfo struct {
+	Name    string
+	Where   string
+	HasArgs bool
 }
@@ -35,6 +35,11 @@ func generateFunctions(toGen []*defs.StructInfo) ([]byte, error) {
 		tableablT,
 		findableT,
 		gettableT,
+		softDeleteableT,
+		versionedT,
+		relatableT,
+		lazyT,
+		scopeT,
 	}
 	for _, s := range toGen {
 		for _, templ := range templateToGoThrough {
@@ -65,27 +65,116 @@ func structInfo(node ast.Node) *defs.StructInfo {
 		return nil
 	}
 
-	return &defs.StructInfo{
-		Name:   typeSpec.Name.Name,
-		Fields: fromFieldList(structType.Fields),
+	fields, relations := fromFieldList(structType.Fields)
+	info := &defs.StructInfo{
+		Name:      typeSpec.Name.Name,
+		Fields:    fields,
+		Relations: relations,
+		HasLazy:   embedsLazy(structType.Fields),
+		Scopes:    scopes(genDecl.Doc, typeSpec.Doc),
 	}
+	for _, field := range info.Fields {
+		if len(field.BelongsTo) > 0 {
+			info.LazyLoaders = append(info.LazyLoaders, defs.LazyInfo{
+				Name:       field.BelongsTo,
+				Type:       field.BelongsTo,
+				Field:      field.Name,
+				References: field.References,
+				IsPointer:  field.IsPointer,
+			})
+		}
+		if field.IsDeletedAt {
+			info.DeletedAtColumn = field.Column
+		}
+		if field.IsVersion {
+			info.VersionColumn = field.Column
+			info.VersionField = field.Name
+			info.VersionType = field.Type
+		}
+	}
+	return info
 }
 
 // TODO(ttacon): deal with primary keys appropriately
-func fromFieldList(fieldList *ast.FieldList) []defs.FieldInfo {
-	var fields []defs.FieldInfo
+func fromFieldList(fieldList *ast.FieldList) ([]defs.FieldInfo, []defs.RelationInfo) {
+	var (
+		fields    []defs.FieldInfo
+		relations []defs.RelationInfo
+	)
 	for _, field := range fieldList.List {
+		if rel, ok := relationInfo(field); ok {
+			for _, name := range field.Names {
+				rel.Name = name.Name
+				relations = append(relations, rel)
+			}
+			continue
+		}
+
 		isPointer, typ := typeInfo(field.Type)
+		opts := pouchTag(field.Tag)
 		for _, name := range field.Names {
-			fields = append(fields, defs.FieldInfo{
-				Name:      name.Name,
-				Column:    columnFromField(name.Name, field.Tag),
-				IsPointer: isPointer,
-				Type:      typ,
-			})
+			info := defs.FieldInfo{
+				Name:        name.Name,
+				Column:      columnFromField(name.Name, field.Tag),
+				IsPointer:   isPointer,
+				IsDeletedAt: hasOption(field.Tag, "deletedAt"),
+				IsVersion:   hasOption(field.Tag, "version"),
+				Type:        typ,
+				BelongsTo:   opts["belongsTo"],
+				References:  opts["references"],
+				Inverse:     opts["inverse"],
+			}
+			if len(info.BelongsTo) > 0 && len(info.References) == 0 {
+				info.References = "ID"
+			}
+			fields = append(fields, info)
 		}
 	}
-	return fields
+	return fields, relations
+}
+
+var relationKinds = map[string]string{
+	"belongsTo": "BelongsTo",
+	"hasOne":    "HasOne",
+	"hasMany":   "HasMany",
+}
+
+// relationInfo determines whether the given field holds related entities,
+// which are tagged as such, i.e.:
+//
+//	Customer *Customer `pouch:"belongsTo,fk=CustomerID"`
+//	Items    []*Item   `pouch:"hasMany,fk=OrderID"`
+//
+// Related entities must be held by pointer.
+func relationInfo(field *ast.Field) (defs.RelationInfo, bool) {
+	opts := pouchTag(field.Tag)
+	for opt, kind := range relationKinds {
+		// a foreign key names what it belongs to, a relation doesn't
+		if v, ok := opts[opt]; !ok || len(v) > 0 {
+			continue
+		}
+
+		expr := field.Type
+		if kind == "HasMany" {
+			arr, ok := expr.(*ast.ArrayType)
+			if !ok {
+				return defs.RelationInfo{}, false
+			}
+			expr = arr.Elt
+		}
+		isPointer, typ := typeInfo(expr)
+		if !isPointer {
+			return defs.RelationInfo{}, false
+		}
+
+		return defs.RelationInfo{
+			Kind:       kind,
+			Type:       typ,
+			ForeignKey: opts["fk"],
+			References: opts["references"],
+		}, true
+	}
+	return defs.RelationInfo{}, false
 }
 
 func columnFromField(name string, t *ast.BasicLit) string {
@@ -99,6 +188,122 @@ func columnFromField(name string, t *ast.BasicLit) string {
 	return name
 }
 
+// embedsLazy reports whether the given fields embed pouch.Lazy.
+func embedsLazy(fieldList *ast.FieldList) bool {
+	for _, field := range fieldList.List {
+		if len(field.Names) > 0 {
+			continue
+		}
+		sel, ok := field.Type.(*ast.SelectorExpr)
+		if !ok {
+			continue
+		}
+		if pkg, ok := sel.X.(*ast.Ident); ok && pkg.Name == "pouch" && sel.Sel.Name == "Lazy" {
+			return true
+		}
+	}
+	return false
+}
+
+// linkCollections gives every entity that foreign keys refer to an
+// accessor for the entities referring to it, i.e. for:
+//
+//	CustomerID int `pouch:"belongsTo=Customer,inverse=Orders"`
+//
+// on Order, Customer gets Orders. Without an inverse, the accessor is
+// named after the referring entity with an "s" tacked on.
+func linkCollections(es []*defs.StructInfo) {
+	var byName = make(map[string]*defs.StructInfo)
+	for _, e := range es {
+		byName[e.Name] = e
+	}
+
+	for _, e := range es {
+		for _, field := range e.Fields {
+			target, ok := byName[field.BelongsTo]
+			if !ok {
+				continue
+			}
+
+			for _, tf := range target.Fields {
+				if tf.Column != field.References {
+					continue
+				}
+
+				name := field.Inverse
+				if len(name) == 0 {
+					name = e.Name + "s"
+				}
+				target.Collections = append(target.Collections, defs.CollectionInfo{
+					Name:       name,
+					ForeignKey: field.Column,
+					Field:      tf.Name,
+				})
+				break
+			}
+		}
+	}
+}
+
+// scopes parses the scopes declared in an entity's doc comment, one per
+// line with its name followed by its where clause, i.e.
+//
+//	// pouch:scope Active deleted_at is null
+//	// pouch:scope ForTenant tenant_id = ?
+func scopes(docs ...*ast.CommentGroup) []defs.ScopeInfo {
+	var infos []defs.ScopeInfo
+	for _, doc := range docs {
+		if doc == nil {
+			continue
+		}
+		for _, line := range strings.Split(doc.Text(), "\n") {
+			if !strings.HasPrefix(line, "pouch:scope ") {
+				continue
+			}
+			parts := strings.SplitN(strings.TrimSpace(strings.TrimPrefix(line, "pouch:scope ")), " ", 2)
+			if len(parts) != 2 {
+				continue
+			}
+			infos = append(infos, defs.ScopeInfo{
+				Name:    parts[0],
+				Where:   strings.TrimSpace(parts[1]),
+				HasArgs: strings.Contains(parts[1], "?"),
+			})
+		}
+	}
+	return infos
+}
+
+// hasOption reports whether the given option is in the comma separated
+// list of options in a field's pouch tag (i.e. `pouch:"deletedAt"` or
+// `pouch:"version"`).
+func hasOption(t *ast.BasicLit, option string) bool {
+	_, ok := pouchTag(t)[option]
+	return ok
+}
+
+// pouchTag parses the comma separated list of options in a field's
+// pouch tag, options may have values (i.e. `pouch:"hasMany,fk=OrderID"`).
+func pouchTag(t *ast.BasicLit) map[string]string {
+	var opts = make(map[string]string)
+	if t == nil {
+		return opts
+	}
+
+	for _, opt := range strings.Split(fromTag(t.Value, "pouch"), ",") {
+		opt = strings.TrimSpace(opt)
+		if len(opt) == 0 {
+			continue
+		}
+		if eq := strings.Index(opt, "="); eq >= 0 {
+			opts[opt[:eq]] = opt[eq+1:]
+		} else {
+			opts[opt] = ""
+		}
+	}
+	return opts
+}
+
 func typeInfo(expr ast.Expr) (bool, string) {
 	if id, ok := expr.(*ast.Ident); ok {
 		return strings.HasPrefix(id.Name, "*"), strings.TrimPrefix(id.Name, "*")
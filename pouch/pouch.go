@@ -144,6 +144,7 @@ func main() {
 	}
 
 	// check structs for field conflicts
+	linkCollections(entities)
 	err = nameConflicts(entities)
 	if err != nil {
 		fmt.Println(dbgenPrmpt, errorP("name conflicts: "+err.Error()))
@@ -296,6 +297,8 @@ var (
 	structTmplt                                  *template.Template
 	identifiableT                                *template.Template
 	insertableT, tableablT, findableT, gettableT *template.Template
+	softDeleteableT, versionedT, relatableT      *template.Template
+	lazyT, scopeT                                *template.Template
 )
 
 func loadTemplates() error {
@@ -330,15 +333,57 @@ func loadTemplates() error {
 		return err
 	}
 
+	softDeleteableT, err = template.New("softDeleteable").Parse(softDeleteableTemplate)
+	if err != nil {
+		return err
+	}
+
+	versionedT, err = template.New("versioned").Parse(versionedTemplate)
+	if err != nil {
+		return err
+	}
+
+	relatableT, err = template.New("relatable").Parse(relatableTemplate)
+	if err != nil {
+		return err
+	}
+
+	lazyT, err = template.New("lazy").Parse(lazyTemplate)
+	if err != nil {
+		return err
+	}
+
+	scopeT, err = template.New("scope").Parse(scopeTemplate)
+	if err != nil {
+		return err
+	}
+
 	return nil
 }
 
 func nameConflicts(es []*defs.StructInfo) error {
 	for _, e := range es {
+		var names = make(map[string]struct{})
 		for _, field := range e.Fields {
 			if _, ok := functionNames[field.Name]; ok {
 				return errors.New(e.Name + "." + field.Name)
 			}
+			names[field.Name] = struct{}{}
+		}
+		for _, rel := range e.Relations {
+			names[rel.Name] = struct{}{}
+		}
+
+		// lazy loaders and collections are named after other entities
+		for _, l := range e.LazyLoaders {
+			if _, ok := names[l.Name]; ok {
+				return errors.New(e.Name + "." + l.Name)
+			}
+		}
+		for _, c := range e.Collections {
+			if _, ok := names[c.Name]; ok {
+				return errors.New(e.Name + "." + c.Name)
+			}
 		}
 	}
 	return nil
@@ -353,6 +398,11 @@ var functionNames = map[string]struct{}{
 	"IdentifiableFields": struct{}{},
 	"GetFieldsFor":       struct{}{},
 	"GetAllFields":       struct{}{},
+	"DeletedAtColumn":    struct{}{},
+	"VersionField":       struct{}{},
+	"SetVersion":         struct{}{},
+	"Relations":          struct{}{},
+	"SetRelated":         struct{}{},
 }
 
 ////////// templates for function generation //////////
@@ -449,3 +499,88 @@ func (g *{{.Name}}) GetAllFields() ([]string, []interface{}) {
     return cols, fields
 }
 `
+
+// SoftDeleteable, only for structs with a field tagged `pouch:"deletedAt"`
+var softDeleteableTemplate = `{{if .DeletedAtColumn}}
+func (s *{{.Name}}) DeletedAtColumn() string {
+    return "{{.DeletedAtColumn}}"
+}
+{{end}}`
+
+// Versioned, only for structs with a field tagged `pouch:"version"`
+var versionedTemplate = `{{if .VersionField}}
+func (v *{{.Name}}) VersionField() (string, int64) {
+    return "{{.VersionColumn}}", int64(v.{{.VersionField}})
+}
+
+func (v *{{.Name}}) SetVersion(version int64) {
+    v.{{.VersionField}} = {{.VersionType}}(version)
+}
+{{end}}`
+
+// Relatable, only for structs with fields tagged as relations
+var relatableTemplate = `{{if .Relations}}
+func (r *{{.Name}}) Relations() []pouch.Relation {
+    return []pouch.Relation{ {{range $i, $v := .Relations}}
+        pouch.Relation{
+            Name:       "{{$v.Name}}",
+            Kind:       pouch.{{$v.Kind}},
+            Related:    &{{$v.Type}}{},
+            ForeignKey: "{{$v.ForeignKey}}",
+            References: "{{$v.References}}",
+        },{{end}}
+    }
+}
+
+func (r *{{.Name}}) SetRelated(name string, related []pouch.Findable) error {
+    switch name { {{range $i, $v := .Relations}}
+    case "{{$v.Name}}":{{if eq $v.Kind "HasMany"}}
+        r.{{$v.Name}} = make([]*{{$v.Type}}, len(related))
+        for i, f := range related {
+            r.{{$v.Name}}[i] = f.(*{{$v.Type}})
+        }{{else}}
+        r.{{$v.Name}} = nil
+        if len(related) > 0 {
+            r.{{$v.Name}} = related[0].(*{{$v.Type}})
+        }{{end}}{{end}}
+    default:
+        return errors.New("unknown relation: " + name)
+    }
+    return nil
+}
+{{end}}`
+
+// Lazy loaders, for fields tagged as foreign keys, and collections, for
+// the entities other entities' foreign keys refer to
+var lazyTemplate = `{{range $i, $v := .LazyLoaders}}
+func (l *{{$.Name}}) {{$v.Name}}(p pouch.Queryable) (*{{$v.Type}}, error) {
+{{if $v.IsPointer}}    if l.{{$v.Field}} == nil {
+        return nil, nil
+    }
+{{end}}    find := func() (pouch.Findable, error) {
+        var related = &{{$v.Type}}{}
+        err := p.Where("{{$v.References}} = ?", l.{{$v.Field}}).Find(related)
+        return related, err
+    }
+{{if $.HasLazy}}
+    f, err := l.Lazy.Load("{{$v.Name}}", l.{{$v.Field}}, find){{else}}
+    f, err := find(){{end}}
+    if err != nil {
+        return nil, err
+    }
+    return f.(*{{$v.Type}}), nil
+}
+{{end}}{{range $i, $v := .Collections}}
+func (c *{{$.Name}}) {{$v.Name}}(p pouch.Queryable) pouch.Query {
+    return p.Where("{{$v.ForeignKey}} = ?", c.{{$v.Field}})
+}
+{{end}}`
+
+// Scopes, named after the entity and the scope, i.e. UserActive
+var scopeTemplate = `{{range $i, $v := .Scopes}}
+func {{$.Name}}{{$v.Name}}({{if $v.HasArgs}}vals ...interface{}{{end}}) pouch.Scope {
+    return func(q pouch.Query) pouch.Query {
+        return q.Where({{printf "%q" $v.Where}}{{if $v.HasArgs}}, vals...{{end}})
+    }
+}
+{{end}}`
@@ -1,10 +1,14 @@
 package main
 
 import (
+	"go/ast"
+	"go/parser"
+	"go/token"
 	"testing"
 
 	. "github.com/smartystreets/goconvey/convey"
 	"github.com/ttacon/go-utils/db/sqlutil"
+	"github.com/ttacon/pouch/pouch/defs"
 ) // not a fan of these style imports
 
 func Test_dbInfoProvided(t *testing.T) {
@@ -124,3 +128,142 @@ func Test_goType(t *testing.T) {
 		}
 	})
 }
+
+func Test_generateFunctions(t *testing.T) {
+	Convey("When generating functions for a struct", t, func() {
+		So(loadTemplates(), ShouldBeNil)
+		s := &defs.StructInfo{
+			Name:  "Food",
+			Table: "Food",
+			Fields: []defs.FieldInfo{
+				{Name: "ID", Column: "ID", IsPrimaryKey: true, Type: "int"},
+				{Name: "Name", Column: "Name", Type: "string"},
+			},
+		}
+
+		Convey("A plain struct should not be soft deleteable or versioned", func() {
+			code, err := generateFunctions([]*defs.StructInfo{s})
+			So(err, ShouldBeNil)
+			So(string(code), ShouldNotContainSubstring, "DeletedAtColumn")
+			So(string(code), ShouldNotContainSubstring, "VersionField")
+		})
+
+		Convey("A struct with a deleted at field should be soft deleteable", func() {
+			s.DeletedAtColumn = "DeletedAt"
+			code, err := generateFunctions([]*defs.StructInfo{s})
+			So(err, ShouldBeNil)
+			So(string(code), ShouldContainSubstring, `return "DeletedAt"`)
+		})
+
+		Convey("A struct with related entities should be relatable", func() {
+			s.Relations = []defs.RelationInfo{
+				{Name: "Customer", Kind: "BelongsTo", Type: "Customer", ForeignKey: "CustomerID"},
+				{Name: "Items", Kind: "HasMany", Type: "Item", ForeignKey: "FoodID"},
+			}
+			code, err := generateFunctions([]*defs.StructInfo{s})
+			So(err, ShouldBeNil)
+			So(string(code), ShouldContainSubstring, "Kind:       pouch.BelongsTo")
+			So(string(code), ShouldContainSubstring, "r.Customer = related[0].(*Customer)")
+			So(string(code), ShouldContainSubstring, "r.Items[i] = f.(*Item)")
+		})
+
+		Convey("A struct with a version field should be versioned", func() {
+			s.VersionColumn, s.VersionField, s.VersionType = "Rev", "Revision", "int"
+			code, err := generateFunctions([]*defs.StructInfo{s})
+			So(err, ShouldBeNil)
+			So(string(code), ShouldContainSubstring, `return "Rev", int64(v.Revision)`)
+			So(string(code), ShouldContainSubstring, "v.Revision = int(version)")
+		})
+	})
+}
+
+func Test_lazyLoaders(t *testing.T) {
+	src := `package shop
+
+type Customer struct {
+	ID   int
+	Name string
+}
+
+type Order struct {
+	pouch.Lazy
+	ID         int
+	CustomerID int ` + "`pouch:\"belongsTo=Customer\"`" + `
+}
+`
+	Convey("When generating functions for structs with foreign keys", t, func() {
+		So(loadTemplates(), ShouldBeNil)
+		f, err := parser.ParseFile(token.NewFileSet(), "shop.go", src, 0)
+		So(err, ShouldBeNil)
+		s := &structCollector{}
+		ast.Inspect(f, s.Visit)
+		So(len(s.structs), ShouldEqual, 2)
+
+		linkCollections(s.structs)
+		So(nameConflicts(s.structs), ShouldBeNil)
+		customer, order := s.structs[0], s.structs[1]
+
+		Convey("the entity with the foreign key should lazily load what it refers to", func() {
+			So(order.HasLazy, ShouldBeTrue)
+			So(len(order.Fields), ShouldEqual, 2)
+			code, err := generateFunctions([]*defs.StructInfo{order})
+			So(err, ShouldBeNil)
+			So(string(code), ShouldContainSubstring,
+				"func (l *Order) Customer(p pouch.Queryable) (*Customer, error) {")
+			So(string(code), ShouldContainSubstring, `p.Where("ID = ?", l.CustomerID).Find(related)`)
+			So(string(code), ShouldContainSubstring, `l.Lazy.Load("Customer", l.CustomerID, find)`)
+		})
+
+		Convey("a lazy loader for a pointer foreign key should load nothing for nil", func() {
+			pointed := *order
+			pointed.LazyLoaders = []defs.LazyInfo{order.LazyLoaders[0]}
+			pointed.LazyLoaders[0].IsPointer = true
+			code, err := generateFunctions([]*defs.StructInfo{&pointed})
+			So(err, ShouldBeNil)
+			So(string(code), ShouldContainSubstring, "if l.CustomerID == nil {\n        return nil, nil")
+		})
+
+		Convey("the entity referred to should have a collection accessor", func() {
+			code, err := generateFunctions([]*defs.StructInfo{customer})
+			So(err, ShouldBeNil)
+			So(string(code), ShouldContainSubstring,
+				"func (c *Customer) Orders(p pouch.Queryable) pouch.Query {")
+			So(string(code), ShouldContainSubstring, `p.Where("CustomerID = ?", c.ID)`)
+		})
+	})
+}
+
+func Test_scopes(t *testing.T) {
+	src := `package shop
+
+// User is someone who shops.
+//
+// pouch:scope Active deleted_at is null AND active = 1
+// pouch:scope ForTenant tenant_id = ?
+type User struct {
+	ID int
+}
+`
+	Convey("When generating functions for structs declaring scopes", t, func() {
+		So(loadTemplates(), ShouldBeNil)
+		f, err := parser.ParseFile(token.NewFileSet(), "shop.go", src, parser.ParseComments)
+		So(err, ShouldBeNil)
+		s := &structCollector{}
+		ast.Inspect(f, s.Visit)
+		So(len(s.structs), ShouldEqual, 1)
+		So(len(s.structs[0].Scopes), ShouldEqual, 2)
+
+		code, err := generateFunctions(s.structs)
+		So(err, ShouldBeNil)
+
+		Convey("scopes without placeholders should take no values", func() {
+			So(string(code), ShouldContainSubstring, "func UserActive() pouch.Scope {")
+			So(string(code), ShouldContainSubstring, `q.Where("deleted_at is null AND active = 1")`)
+		})
+
+		Convey("scopes with placeholders should take their values", func() {
+			So(string(code), ShouldContainSubstring, "func UserForTenant(vals ...interface{}) pouch.Scope {")
+			So(string(code), ShouldContainSubstring, `q.Where("tenant_id = ?", vals...)`)
+		})
+	})
+}
@@ -0,0 +1,65 @@
+// Package prom adapts the Prometheus client to the metrics of an
+// Instrumented pouch, i.e.
+//
+//	p = pouch.Instrumented(p, prom.Registry(prometheus.DefaultRegisterer))
+//	http.Handle("/metrics", promhttp.Handler())
+package prom
+
+import (
+	"errors"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/ttacon/pouch"
+)
+
+// Registry returns a pouch.MetricsRegistry which makes CounterVecs and
+// HistogramVecs, registered with the given Registerer. Metrics that are
+// already registered (i.e. by another Instrumented pouch) are reused,
+// other failures to register them panic, like MustRegister does.
+func Registry(r prometheus.Registerer) pouch.MetricsRegistry {
+	return registry{r: r}
+}
+
+type registry struct {
+	r prometheus.Registerer
+}
+
+func (r registry) Counter(name, help string, labels ...string) pouch.Counter {
+	vec := prometheus.NewCounterVec(prometheus.CounterOpts{Name: name, Help: help}, labels)
+	return counter{r.register(vec).(*prometheus.CounterVec)}
+}
+
+func (r registry) Histogram(name, help string, buckets []float64, labels ...string) pouch.Histogram {
+	vec := prometheus.NewHistogramVec(prometheus.HistogramOpts{Name: name, Help: help, Buckets: buckets}, labels)
+	return histogram{r.register(vec).(*prometheus.HistogramVec)}
+}
+
+// register registers the given collector, or returns the one that was
+// registered in its stead.
+func (r registry) register(c prometheus.Collector) prometheus.Collector {
+	err := r.r.Register(c)
+	if err == nil {
+		return c
+	}
+	var already prometheus.AlreadyRegisteredError
+	if errors.As(err, &already) {
+		return already.ExistingCollector
+	}
+	panic(err)
+}
+
+type counter struct {
+	vec *prometheus.CounterVec
+}
+
+func (c counter) Add(delta float64, labelValues ...string) {
+	c.vec.WithLabelValues(labelValues...).Add(delta)
+}
+
+type histogram struct {
+	vec *prometheus.HistogramVec
+}
+
+func (h histogram) Observe(value float64, labelValues ...string) {
+	h.vec.WithLabelValues(labelValues...).Observe(value)
+}
@@ -0,0 +1,190 @@
+package pouch
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+)
+
+// RelationKind describes which side of a relationship holds the
+// foreign key.
+type RelationKind int
+
+const (
+	// BelongsTo relations are held by a foreign key on the entity
+	// itself (i.e. an Order's CustomerID).
+	BelongsTo RelationKind = iota
+	// HasOne relations are held by a foreign key on the single related
+	// entity (i.e. a Customer's Profile with a CustomerID).
+	HasOne
+	// HasMany relations are held by a foreign key on each of the related
+	// entities (i.e. an Order's Items, each with an OrderID).
+	HasMany
+)
+
+// A Relation describes how an entity is related to other entities.
+type Relation struct {
+	// Name identifies the relation, i.e. "Customer".
+	Name string
+	Kind RelationKind
+	// Related is an example of the related entities, which is used
+	// to retrieve them.
+	Related Findable
+	// ForeignKey is the column holding the relation, on the entity for
+	// BelongsTo relations and on the related entities otherwise.
+	ForeignKey string
+	// References is the column the foreign key refers to, it defaults
+	// to the first identifying column of the referenced entity.
+	References string
+}
+
+// A Relatable entity is one which knows how it is related to other
+// entities and how to attach them to itself.
+type Relatable interface {
+	Relations() []Relation
+	// SetRelated attaches the entities retrieved for the named relation.
+	SetRelated(name string, related []Findable) error
+}
+
+// Preload retrieves the named relations for all of the given entities,
+// with a single query per relation (the foreign keys are batched into
+// an "in" constraint), and attaches them to the entities they belong to.
+// The Queryable's Where is used once per relation, so it should start
+// a fresh Query each time, as a Pouch's does.
+func Preload(q Queryable, entities []Findable, relations ...string) error {
+	if len(entities) == 0 {
+		return nil
+	}
+
+	for _, name := range relations {
+		var rel *Relation
+		if r, ok := entities[0].(Relatable); ok {
+			for _, candidate := range r.Relations() {
+				if candidate.Name == name {
+					rel = &candidate
+					break
+				}
+			}
+		}
+		if rel == nil {
+			return errors.New("unknown relation: " + name)
+		}
+		if err := preload(q, entities, *rel); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func preload(q Queryable, entities []Findable, rel Relation) error {
+	if rel.Related == nil {
+		return errors.New("relation " + rel.Name + " has no related entity")
+	}
+
+	// figure out which column on each side of the relation to match
+	var ownCol, relatedCol = rel.References, rel.ForeignKey
+	if rel.Kind == BelongsTo {
+		ownCol, relatedCol = rel.ForeignKey, rel.References
+		if len(relatedCol) == 0 {
+			relatedCol = identityColumn(rel.Related)
+		}
+	} else if len(ownCol) == 0 {
+		ownCol = identityColumn(entities[0])
+	}
+	if len(ownCol) == 0 || len(relatedCol) == 0 {
+		return errors.New("relation " + rel.Name + " is missing its key columns")
+	}
+
+	var (
+		keys []interface{}
+		seen = make(map[string]struct{})
+	)
+	for _, e := range entities {
+		v, ok := columnValue(e, ownCol)
+		if !ok {
+			continue
+		}
+		if _, ok := seen[keyOf(v)]; !ok {
+			seen[keyOf(v)] = struct{}{}
+			keys = append(keys, v)
+		}
+	}
+
+	var related []Findable
+	if len(keys) > 0 {
+		placeholders := "?" + strings.Repeat(", ?", len(keys)-1)
+		err := q.Where(relatedCol+" in ("+placeholders+")", keys...).
+			FindEntities(rel.Related, &related)
+		if err != nil {
+			return err
+		}
+	}
+
+	var byKey = make(map[string][]Findable)
+	for _, r := range related {
+		if v, ok := columnValue(r, relatedCol); ok {
+			byKey[keyOf(v)] = append(byKey[keyOf(v)], r)
+		}
+	}
+
+	for _, e := range entities {
+		r, ok := e.(Relatable)
+		if !ok {
+			return fmt.Errorf("%T is not relatable", e)
+		}
+
+		var matches []Findable
+		if v, ok := columnValue(e, ownCol); ok {
+			matches = byKey[keyOf(v)]
+		}
+		if err := r.SetRelated(rel.Name, matches); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// identityColumn returns the first identifying column of an entity.
+func identityColumn(i Identifiable) string {
+	cols, _ := i.IdentifiableFields()
+	if len(cols) == 0 {
+		return ""
+	}
+	return cols[0]
+}
+
+// columnValue retrieves the (non-nil) value of the given column from
+// an entity.
+func columnValue(g Gettable, col string) (interface{}, bool) {
+	fields := g.GetFieldsFor([]string{col})
+	if len(fields) == 0 {
+		return nil, false
+	}
+	return deref(fields[0])
+}
+
+// deref follows pointers to the (non-nil) value they point to.
+func deref(field interface{}) (interface{}, bool) {
+	if field == nil {
+		return nil, false
+	}
+
+	v := reflect.ValueOf(field)
+	for v.Kind() == reflect.Ptr || v.Kind() == reflect.Interface {
+		if v.IsNil() {
+			return nil, false
+		}
+		v = v.Elem()
+	}
+	return v.Interface(), true
+}
+
+// keyOf normalizes column values, which may have been scanned into
+// different types on either side of a relation, so they can be matched.
+func keyOf(v interface{}) string {
+	if b, ok := v.([]byte); ok {
+		return string(b)
+	}
+	return fmt.Sprint(v)
+}
@@ -0,0 +1,245 @@
+package pouch
+
+import (
+	"context"
+	"database/sql/driver"
+	"errors"
+	"math/rand"
+	"time"
+)
+
+// A Transactional Pouch can run operations in a transaction.
+type Transactional interface {
+	// RunInTx runs fn with a Pouch whose operations all run in one
+	// transaction, which is committed if fn returns nil and rolled back
+	// otherwise.
+	RunInTx(ctx context.Context, fn func(p Pouch) error) error
+}
+
+// A RetryPolicy decides which operations are retried, how many times and
+// how long apart. Idempotent operations (all but Create, CreateAll and
+// updates of Versioned entities) that fail with a transient error are
+// retried, after a delay which starts at BaseDelay and doubles with every
+// retry, up to MaxDelay, of which a random half is jitter.
+type RetryPolicy struct {
+	// MaxAttempts is how many times an operation is run at most,
+	// including the first.
+	MaxAttempts int
+	BaseDelay   time.Duration
+	MaxDelay    time.Duration
+	// Retryable reports whether an error is transient, IsTransient is
+	// used if it's nil.
+	Retryable func(err error) bool
+	// RetryCreates allows retrying the operations that aren't idempotent:
+	// Create and CreateAll, which create entities twice if an attempt
+	// that succeeded reports an error, and updates of Versioned entities,
+	// which then fail with a *ConflictError.
+	RetryCreates bool
+}
+
+// DefaultRetryPolicy runs operations three times at most, waiting 5-10ms
+// before the first retry and 10-20ms before the second.
+var DefaultRetryPolicy = RetryPolicy{
+	MaxAttempts: 3,
+	BaseDelay:   10 * time.Millisecond,
+	MaxDelay:    time.Second,
+}
+
+// IsTransient reports whether an error is one that running the operation
+// again may not have, i.e. a dropped connection or a timeout.
+func IsTransient(err error) bool {
+	if err == nil || errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return false
+	}
+	var temporary interface {
+		Temporary() bool
+	}
+	var timeout interface {
+		Timeout() bool
+	}
+	return errors.Is(err, driver.ErrBadConn) ||
+		errors.As(err, &temporary) && temporary.Temporary() ||
+		errors.As(err, &timeout) && timeout.Timeout()
+}
+
+func (p RetryPolicy) retryable(err error) bool {
+	if p.Retryable != nil {
+		return p.Retryable(err)
+	}
+	return IsTransient(err)
+}
+
+// delay is how long to wait before the given retry, the first being 1.
+func (p RetryPolicy) delay(retry int) time.Duration {
+	d := p.BaseDelay
+	for i := 1; i < retry && (p.MaxDelay <= 0 || d < p.MaxDelay); i++ {
+		d *= 2
+	}
+	if p.MaxDelay > 0 && d > p.MaxDelay {
+		d = p.MaxDelay
+	}
+	if d <= 0 {
+		return 0
+	}
+	half := d / 2
+	return half + time.Duration(rand.Int63n(int64(d-half)+1))
+}
+
+// Do runs f until it succeeds, fails with an error that isn't retryable,
+// or has been run MaxAttempts times, waiting between runs unless the
+// context is done. Operations that aren't idempotent are only retried if
+// the policy allows retrying creates.
+func (p RetryPolicy) Do(ctx context.Context, idempotent bool, f func() error) error {
+	for attempt := 1; ; attempt++ {
+		err := f()
+		if err == nil || attempt >= p.MaxAttempts || !p.retryable(err) || !idempotent && !p.RetryCreates {
+			return err
+		}
+
+		timer := time.NewTimer(p.delay(attempt))
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return err
+		case <-timer.C:
+		}
+	}
+}
+
+// Retrying returns a view of the given Pouch which retries operations
+// that fail with a transient error as the policy says, with the context
+// of the Query (see Queryable.WithContext) cutting waits short. The view
+// is Transactional, running transactions on the Pouch (and the views it
+// wraps) if it is, and retries them whole, so their funcs should have no
+// effects outside of them.
+//
+// The Pouch should not retry operations itself (i.e. an SQL pouch made
+// with impl.Retry), as every attempt of the view's would then be retried
+// by the Pouch, multiplying the attempts.
+func Retrying(p Pouch, policy RetryPolicy) Pouch {
+	return newRetryingQuery(blank(p), policy)
+}
+
+type retryingQuery struct {
+	decorator
+	policy RetryPolicy
+}
+
+func newRetryingQuery(inner Query, policy RetryPolicy) *retryingQuery {
+	return &retryingQuery{
+		decorator: decorator{
+			inner: inner,
+			wrap: func(q Query) Query {
+				return newRetryingQuery(q, policy)
+			},
+		},
+		policy: policy,
+	}
+}
+
+func (s *retryingQuery) retry(f func() error) error {
+	return s.policy.Do(s.inner.Context(), true, f)
+}
+
+// retryUpdate retries an update of the given entities, unless they're
+// Versioned (see RetryPolicy).
+func (s *retryingQuery) retryUpdate(us []Updateable, f func() error) error {
+	for _, u := range us {
+		if _, ok := u.(Versioned); ok {
+			return s.policy.Do(s.inner.Context(), false, f)
+		}
+	}
+	return s.retry(f)
+}
+
+// RunInTx retries the whole transaction, which it runs on the wrapped
+// Query: the transaction's own operations aren't retried, as a
+// transaction that failed has to be run again from its start.
+func (s *retryingQuery) RunInTx(ctx context.Context, fn func(p Pouch) error) error {
+	t, ok := s.inner.(Transactional)
+	if !ok {
+		return errors.New("pouch does not support transactions")
+	}
+	return s.policy.Do(ctx, true, func() error {
+		return t.RunInTx(ctx, fn)
+	})
+}
+
+func (s *retryingQuery) Find(i Findable) error {
+	return s.retry(func() error { return s.inner.Find(i) })
+}
+
+func (s *retryingQuery) FindAll(fs []Findable) error {
+	return s.retry(func() error { return s.inner.FindAll(fs) })
+}
+
+func (s *retryingQuery) FindEntities(template Findable, res *[]Findable) error {
+	var found = len(*res)
+	return s.retry(func() error {
+		// drop what a failed attempt found
+		*res = (*res)[:found]
+		return s.inner.FindEntities(template, res)
+	})
+}
+
+func (s *retryingQuery) FindPage(template Findable, res *[]Findable) (next, prev Cursor, err error) {
+	var found = len(*res)
+	err = s.retry(func() error {
+		*res = (*res)[:found]
+		next, prev, err = s.inner.FindPage(template, res)
+		return err
+	})
+	return next, prev, err
+}
+
+func (s *retryingQuery) Create(c Createable) error {
+	return s.policy.Do(s.inner.Context(), false, func() error { return s.inner.Create(c) })
+}
+
+func (s *retryingQuery) CreateAll(cs []Createable) error {
+	return s.policy.Do(s.inner.Context(), false, func() error { return s.inner.CreateAll(cs) })
+}
+
+func (s *retryingQuery) Update(u Updateable) error {
+	return s.retryUpdate([]Updateable{u}, func() error { return s.inner.Update(u) })
+}
+
+func (s *retryingQuery) UpdateColumns(u Updateable, cols ...string) error {
+	return s.retryUpdate([]Updateable{u}, func() error { return s.inner.UpdateColumns(u, cols...) })
+}
+
+func (s *retryingQuery) UpdateAll(us []Updateable) error {
+	return s.retryUpdate(us, func() error { return s.inner.UpdateAll(us) })
+}
+
+func (s *retryingQuery) Delete(d Deleteable) error {
+	return s.retry(func() error { return s.inner.Delete(d) })
+}
+
+func (s *retryingQuery) DeleteAll(ds []Deleteable) error {
+	return s.retry(func() error { return s.inner.DeleteAll(ds) })
+}
+
+func (s *retryingQuery) HardDelete(d Deleteable) error {
+	return s.retry(func() error { return s.inner.HardDelete(d) })
+}
+
+func (s *retryingQuery) Restore(sd SoftDeleteable) error {
+	return s.retry(func() error { return s.inner.Restore(sd) })
+}
+
+func (s *retryingQuery) UpdateWhere(t Tableable, assignments map[string]interface{}) (n int64, err error) {
+	err = s.retry(func() error {
+		n, err = s.inner.UpdateWhere(t, assignments)
+		return err
+	})
+	return n, err
+}
+
+func (s *retryingQuery) DeleteWhere(t Tableable) (n int64, err error) {
+	err = s.retry(func() error {
+		n, err = s.inner.DeleteWhere(t)
+		return err
+	})
+	return n, err
+}
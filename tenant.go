@@ -0,0 +1,377 @@
+package pouch
+
+import (
+	"database/sql"
+	"errors"
+	"reflect"
+)
+
+// TenantPouch returns a Pouch which confines every operation to the
+// entities of a single tenant, whose ID is held in the given column of
+// every table. Reads only see the tenant's entities, created entities
+// are given the tenant's ID, and updates and deletes of entities that
+// belong to another tenant fail with ErrWrongTenant.
+//
+// Updates and deletes are checked against what is stored for the
+// entity, so the entities they are given must be Findable, and updated
+// entities must still hold the tenant's ID. They are then confined to
+// the tenant's entities like reads are, and fail with ErrWrongTenant
+// when the entity was handed to another tenant in between, if the pouch
+// is strict (a pouch that isn't skips missing entities regardless).
+// Pouches that don't honor criterions, like dynamic ones, only get the
+// check. The entities that are created must be Gettable.
+//
+// Updating or deleting by criterions takes criterions besides the
+// tenant's, unless the query AllowUnconstrained, and relations preloaded
+// for the entities that are found are confined to the tenant as well.
+func TenantPouch(inner Pouch, column string, tenantID interface{}) Pouch {
+	s := &tenantQuery{
+		pouch:  inner,
+		column: column,
+		tenant: tenantID,
+	}
+	return s.with(blank(inner))
+}
+
+type tenantQuery struct {
+	decorator
+	// the pouch, for preloading relations
+	pouch  Pouch
+	column string
+	tenant interface{}
+
+	// whether criterions were added, which Find then uses instead of
+	// the entity's identity
+	constrained bool
+	// whether updating or deleting without criterions was allowed
+	allowed  bool
+	preloads []string
+}
+
+// with returns a copy of the query, wrapping the given one.
+func (s *tenantQuery) with(inner Query) *tenantQuery {
+	c := *s
+	c.preloads = c.preloads[:len(c.preloads):len(c.preloads)]
+	c.decorator = decorator{
+		inner: inner,
+		wrap: func(q Query) Query {
+			return c.with(q)
+		},
+		within: func(tx Pouch) Query {
+			return TenantPouch(tx, c.column, c.tenant).(Query)
+		},
+	}
+	return &c
+}
+
+func (s *tenantQuery) Where(frag string, vals ...interface{}) Query {
+	q := s.with(s.inner.Where(frag, vals...))
+	q.constrained = true
+	return q
+}
+
+func (s *tenantQuery) AllowUnconstrained() Query {
+	q := s.with(s.inner.AllowUnconstrained())
+	q.allowed = true
+	return q
+}
+
+// Preload has the query preload the relations itself, through a view of
+// the pouch confined to the tenant, rather than the wrapped query which
+// would find them through the bare pouch.
+func (s *tenantQuery) Preload(relations ...string) Query {
+	q := s.with(s.inner)
+	q.preloads = append(q.preloads, relations...)
+	return q
+}
+
+// preload preloads the relations of the entities found past the given
+// index.
+func (s *tenantQuery) preload(res *[]Findable, from int) error {
+	if len(s.preloads) == 0 {
+		return nil
+	}
+	return Preload(TenantPouch(s.pouch, s.column, s.tenant), (*res)[from:], s.preloads...)
+}
+
+// scoped returns the wrapped query, confined to the tenant.
+func (s *tenantQuery) scoped() Query {
+	return s.inner.Where(s.column+" = ?", s.tenant)
+}
+
+// owns reports whether a column value is the tenant's ID.
+func (s *tenantQuery) owns(v interface{}, ok bool) bool {
+	return ok && keyOf(v) == keyOf(s.tenant)
+}
+
+// filter drops the entities that aren't the tenant's from the results
+// found past the given index, for pouches that don't honor criterions.
+func (s *tenantQuery) filter(res *[]Findable, from int) {
+	var kept = (*res)[:from]
+	for _, f := range (*res)[from:] {
+		if s.owns(columnValue(f, s.column)) {
+			kept = append(kept, f)
+		}
+	}
+	*res = kept
+}
+
+func (s *tenantQuery) Find(i Findable) error {
+	var q = s.scoped()
+	if !s.constrained {
+		cols, vals := i.IdentifiableFields()
+		if len(cols) != len(vals) {
+			return errors.New("entity has a different number of identifying columns and values")
+		}
+		for j, col := range cols {
+			q = q.Where(col+" = ?", vals[j])
+		}
+	}
+
+	if err := q.Find(i); err != nil {
+		return err
+	}
+	if !s.owns(columnValue(i, s.column)) {
+		return ErrWrongTenant
+	}
+	return nil
+}
+
+func (s *tenantQuery) FindAll(fs []Findable) error {
+	if err := s.scoped().FindAll(fs); err != nil {
+		return err
+	}
+	for _, f := range fs {
+		if !s.owns(columnValue(f, s.column)) {
+			return ErrWrongTenant
+		}
+	}
+	return nil
+}
+
+func (s *tenantQuery) FindEntities(template Findable, res *[]Findable) error {
+	var found = len(*res)
+	if err := s.scoped().FindEntities(template, res); err != nil {
+		return err
+	}
+	s.filter(res, found)
+	return s.preload(res, found)
+}
+
+func (s *tenantQuery) FindPage(template Findable, res *[]Findable) (Cursor, Cursor, error) {
+	var found = len(*res)
+	next, prev, err := s.scoped().FindPage(template, res)
+	if err != nil {
+		return "", "", err
+	}
+	s.filter(res, found)
+	if err := s.preload(res, found); err != nil {
+		return "", "", err
+	}
+	return next, prev, nil
+}
+
+func (s *tenantQuery) Create(c Createable) error {
+	if err := s.stamp(c); err != nil {
+		return err
+	}
+	return s.inner.Create(c)
+}
+
+func (s *tenantQuery) CreateAll(cs []Createable) error {
+	for _, c := range cs {
+		if err := s.stamp(c); err != nil {
+			return err
+		}
+	}
+	return s.inner.CreateAll(cs)
+}
+
+func (s *tenantQuery) Update(u Updateable) error {
+	if err := s.checkUpdate(u); err != nil {
+		return err
+	}
+	return raced(s.scoped().Update(u))
+}
+
+func (s *tenantQuery) UpdateColumns(u Updateable, cols ...string) error {
+	if err := s.checkUpdate(u); err != nil {
+		return err
+	}
+	return raced(s.scoped().UpdateColumns(u, cols...))
+}
+
+func (s *tenantQuery) UpdateAll(us []Updateable) error {
+	for _, u := range us {
+		if err := s.checkUpdate(u); err != nil {
+			return err
+		}
+	}
+	return raced(s.scoped().UpdateAll(us))
+}
+
+func (s *tenantQuery) Delete(d Deleteable) error {
+	if err := s.check(d, s.inner); err != nil {
+		return err
+	}
+	return raced(s.scoped().Delete(d))
+}
+
+func (s *tenantQuery) DeleteAll(ds []Deleteable) error {
+	for _, d := range ds {
+		if err := s.check(d, s.inner); err != nil {
+			return err
+		}
+	}
+	return raced(s.scoped().DeleteAll(ds))
+}
+
+func (s *tenantQuery) HardDelete(d Deleteable) error {
+	if err := s.check(d, s.inner.WithDeleted()); err != nil {
+		return err
+	}
+	return raced(s.scoped().HardDelete(d))
+}
+
+func (s *tenantQuery) Restore(sd SoftDeleteable) error {
+	if err := s.check(sd, s.inner.WithDeleted()); err != nil {
+		return err
+	}
+	return raced(s.scoped().Restore(sd))
+}
+
+// raced reports entities that went missing from the tenant's between
+// their check and their write as not being the tenant's.
+func raced(err error) error {
+	var notFound *NotFoundError
+	if errors.As(err, &notFound) {
+		return ErrWrongTenant
+	}
+	return err
+}
+
+func (s *tenantQuery) UpdateWhere(t Tableable, assignments map[string]interface{}) (int64, error) {
+	if !s.constrained && !s.allowed {
+		return 0, ErrUnconstrained
+	}
+	if v, ok := assignments[s.column]; ok && !s.owns(v, true) {
+		return 0, ErrWrongTenant
+	}
+	return s.scoped().UpdateWhere(t, assignments)
+}
+
+// DeleteWhere, like UpdateWhere, refuses to run with only the tenant's
+// criterion, which would otherwise have every query constrained.
+func (s *tenantQuery) DeleteWhere(t Tableable) (int64, error) {
+	if !s.constrained && !s.allowed {
+		return 0, ErrUnconstrained
+	}
+	return s.scoped().DeleteWhere(t)
+}
+
+// stamp sets the tenant's ID on an entity that is being created, unless
+// it was already given another tenant's.
+func (s *tenantQuery) stamp(c Createable) error {
+	g, ok := c.(Gettable)
+	if !ok {
+		return errors.New("cannot set the tenant of an entity that isn't Gettable")
+	}
+	if v, ok := columnValue(g, s.column); ok && !isZero(v) && !s.owns(v, true) {
+		return ErrWrongTenant
+	}
+	return setColumn(g, s.column, s.tenant)
+}
+
+// checkUpdate refuses updates of entities that aren't the tenant's, or
+// that would hand them to another tenant.
+func (s *tenantQuery) checkUpdate(u Updateable) error {
+	vals := u.FieldsFor([]string{s.column})
+	if len(vals) == 0 || !s.owns(deref(vals[0])) {
+		return ErrWrongTenant
+	}
+	return s.check(u, s.inner)
+}
+
+// check refuses writes to entities that aren't the tenant's, going by
+// what the given query finds stored for them.
+func (s *tenantQuery) check(e Identifiable, q Query) error {
+	f, ok := e.(Findable)
+	if !ok {
+		return errors.New("cannot check the tenant of an entity that isn't Findable")
+	}
+	var stored = f.FindableCopy()
+	cols, vals := f.IdentifiableFields()
+	for i, col := range cols {
+		if err := setColumn(stored, col, vals[i]); err != nil {
+			return err
+		}
+	}
+
+	c := s.with(q)
+	c.constrained = false
+	err := c.Find(stored)
+	if err == sql.ErrNoRows {
+		return ErrWrongTenant
+	}
+	return err
+}
+
+// setColumn sets the field of an entity for the given column.
+func setColumn(g Gettable, col string, val interface{}) error {
+	fields := g.GetFieldsFor([]string{col})
+	if len(fields) == 0 || fields[0] == nil {
+		return errors.New("entity has no field for column: " + col)
+	}
+
+	dst := reflect.ValueOf(fields[0])
+	if dst.Kind() != reflect.Ptr || dst.IsNil() {
+		return errors.New("entity has no settable field for column: " + col)
+	}
+	dst = dst.Elem()
+
+	// nullable columns are held by pointers
+	if dst.Kind() == reflect.Ptr {
+		v, err := convert(val, dst.Type().Elem(), col)
+		if err != nil {
+			return err
+		}
+		p := reflect.New(dst.Type().Elem())
+		p.Elem().Set(v)
+		dst.Set(p)
+		return nil
+	}
+
+	v, err := convert(val, dst.Type(), col)
+	if err != nil {
+		return err
+	}
+	dst.Set(v)
+	return nil
+}
+
+// convert converts a value to the type of a column's field, as long as
+// they're the same kind of value (i.e. numbers of different sizes).
+func convert(val interface{}, t reflect.Type, col string) (reflect.Value, error) {
+	v := reflect.ValueOf(val)
+	if !v.IsValid() {
+		return reflect.Zero(t), nil
+	}
+	if v.Type().AssignableTo(t) {
+		return v, nil
+	}
+	if isNumber(v.Kind()) && isNumber(t.Kind()) {
+		return v.Convert(t), nil
+	}
+	if v.Kind() == t.Kind() && v.Type().ConvertibleTo(t) {
+		return v.Convert(t), nil
+	}
+	return reflect.Value{}, errors.New("cannot set column " + col + " to a " + v.Type().String())
+}
+
+func isNumber(k reflect.Kind) bool {
+	return k >= reflect.Int && k <= reflect.Float64
+}
+
+func isZero(v interface{}) bool {
+	return reflect.DeepEqual(v, reflect.Zero(reflect.TypeOf(v)).Interface())
+}
@@ -0,0 +1,147 @@
+package pouch
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// A Tracer starts spans, it is meant to be adapted to a tracing library
+// (i.e. OpenTelemetry, whose trace.Tracer it mirrors), see MemoryTracer
+// for one that keeps the spans it starts.
+type Tracer interface {
+	// Start starts a span, as a child of the span in the given context,
+	// if any, and returns a context carrying it.
+	Start(ctx context.Context, name string) (context.Context, Span)
+}
+
+// A Span is an operation being traced.
+type Span interface {
+	SetAttribute(key string, value interface{})
+	RecordError(err error)
+	End()
+}
+
+// The attributes of the spans of a Traced pouch.
+const (
+	// AttrOperation is the operation the span is for, i.e. "Find".
+	AttrOperation = "pouch.operation"
+	AttrTable     = "pouch.table"
+	// AttrRows is how many entities the operation found or wrote, it's
+	// left out for the operations that can't tell (see MetricRows).
+	AttrRows = "pouch.rows"
+	// AttrStatement is the statement the operation ran, without the
+	// values bound to it, if the pouch runs statements.
+	AttrStatement = "db.statement"
+)
+
+type spanKey struct{}
+
+// ContextWithSpan returns a context which carries the given span, Tracers
+// should return one from Start.
+func ContextWithSpan(ctx context.Context, span Span) context.Context {
+	return context.WithValue(ctx, spanKey{}, span)
+}
+
+// SpanFromContext returns the span the given context carries, if any,
+// pouches use it to add what they know of an operation (i.e. the
+// statement they ran) to its span.
+func SpanFromContext(ctx context.Context) (Span, bool) {
+	span, ok := ctx.Value(spanKey{}).(Span)
+	return span, ok
+}
+
+// Traced returns a view of the given Pouch which starts a span, named
+// "pouch." followed by the operation, for every operation that reads or
+// writes entities. Spans are children of the span in the context of the
+// Query (see Queryable.WithContext), they are passed on to the Pouch in
+// the context and have the operation, the table and the number of rows
+// as attributes. Errors are recorded on them.
+func Traced(p Pouch, t Tracer) Pouch {
+	return newObservedQuery(blank(p), spanObserver(t))
+}
+
+// spanObserver starts the span of an operation, and passes it on in the
+// context of the Query the operation runs with.
+func spanObserver(t Tracer) observer {
+	return func(q Query, op, table string) (Query, func(rows int64, err error)) {
+		ctx, span := t.Start(q.Context(), "pouch."+op)
+		span.SetAttribute(AttrOperation, op)
+		span.SetAttribute(AttrTable, table)
+		return q.WithContext(ctx), func(rows int64, err error) {
+			if err != nil {
+				span.RecordError(err)
+			} else if rows != unknownRows {
+				span.SetAttribute(AttrRows, rows)
+			}
+			span.End()
+		}
+	}
+}
+
+// MemoryTracer is a Tracer which keeps the spans it starts, for tests.
+type MemoryTracer struct {
+	mu    sync.Mutex
+	spans []*MemorySpan
+}
+
+// A MemorySpan is a span started by a MemoryTracer.
+type MemorySpan struct {
+	Name      string
+	Parent    *MemorySpan
+	StartTime time.Time
+	// EndTime is zero until the span has ended.
+	EndTime    time.Time
+	Attributes map[string]interface{}
+	Errors     []error
+
+	mu sync.Mutex
+}
+
+func (t *MemoryTracer) Start(ctx context.Context, name string) (context.Context, Span) {
+	var span = &MemorySpan{
+		Name:       name,
+		StartTime:  time.Now(),
+		Attributes: make(map[string]interface{}),
+	}
+	if parent, ok := SpanFromContext(ctx); ok {
+		span.Parent, _ = parent.(*MemorySpan)
+	}
+	t.mu.Lock()
+	t.spans = append(t.spans, span)
+	t.mu.Unlock()
+	return ContextWithSpan(ctx, span), span
+}
+
+// Spans returns the spans the tracer started, in the order it started
+// them.
+func (t *MemoryTracer) Spans() []*MemorySpan {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return append([]*MemorySpan(nil), t.spans...)
+}
+
+// Reset forgets the spans the tracer started.
+func (t *MemoryTracer) Reset() {
+	t.mu.Lock()
+	t.spans = nil
+	t.mu.Unlock()
+}
+
+func (s *MemorySpan) SetAttribute(key string, value interface{}) {
+	s.mu.Lock()
+	s.Attributes[key] = value
+	s.mu.Unlock()
+}
+
+func (s *MemorySpan) RecordError(err error) {
+	s.mu.Lock()
+	s.Errors = append(s.Errors, err)
+	s.mu.Unlock()
+}
+
+func (s *MemorySpan) End() {
+	s.mu.Lock()
+	s.EndTime = time.Now()
+	s.mu.Unlock()
+}
@@ -0,0 +1,47 @@
+package pouch
+
+import "reflect"
+
+// A Trackable entity remembers the values it was last retrieved from,
+// or stored in, a Storage system with, so that updating it only needs
+// to write the columns that have since changed.
+type Trackable interface {
+	// Snapshot records the given values as the current state of their
+	// columns in the Storage system, other columns are left as they were.
+	Snapshot(cols []string, vals []interface{})
+	// Changed returns which of the given columns have values that
+	// differ from the last snapshot, it reports false if no snapshot
+	// has been taken.
+	Changed(cols []string, vals []interface{}) ([]string, bool)
+}
+
+// A Tracker can be embedded in an entity to make it Trackable.
+type Tracker struct {
+	snapshot map[string]interface{}
+}
+
+func (t *Tracker) Snapshot(cols []string, vals []interface{}) {
+	if t.snapshot == nil {
+		t.snapshot = make(map[string]interface{}, len(cols))
+	}
+	for i, col := range cols {
+		if i < len(vals) {
+			t.snapshot[col] = vals[i]
+		}
+	}
+}
+
+func (t *Tracker) Changed(cols []string, vals []interface{}) ([]string, bool) {
+	if t.snapshot == nil {
+		return nil, false
+	}
+
+	var changed []string
+	for i, col := range cols {
+		old, ok := t.snapshot[col]
+		if !ok || i >= len(vals) || !reflect.DeepEqual(old, vals[i]) {
+			changed = append(changed, col)
+		}
+	}
+	return changed, true
+}